@@ -2,4 +2,7 @@ module github.com/jbhicks/jbhicks.dev
 
 go 1.15
 
-require github.com/gin-gonic/gin v1.10.0
+require (
+	github.com/gin-gonic/gin v1.10.0
+	golang.org/x/net v0.25.0
+)
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	alertRulesFile    = "alert-rules.json"
+	alertsSentFile    = "alerts-sent.json"
+	alertMaxAttempts  = 4
+	alertRetryBackoff = 2 * time.Second     // Doubled after each failed attempt
+	alertMaxItemAge   = 48 * time.Hour      // Older items never alert, so a new rule doesn't flood the webhook
+	alertSentRetained = 30 * 24 * time.Hour // How long delivered alerts are remembered
+)
+
+// AlertRule posts news items matching any of its keywords to a webhook
+type AlertRule struct {
+	Name     string   `json:"name"`
+	Keywords []string `json:"keywords"` // Matched case-insensitively against title, description and tags
+	Sources  []string `json:"sources"`  // Only alert on these feeds, all feeds when empty
+	Webhook  string   `json:"webhook"`
+	Format   string   `json:"format"` // "json" (default), "ntfy" or "slack"
+	Secret   string   `json:"secret"` // Signs json payloads in the X-Signature-256 header when set
+}
+
+// AlertPayload is the body posted for json format rules
+type AlertPayload struct {
+	Rule     string    `json:"rule"`
+	Keywords []string  `json:"keywords"` // The keywords that matched
+	Item     AlertItem `json:"item"`
+	SentAt   time.Time `json:"sentAt"`
+}
+
+// AlertItem is the news item an alert fired for
+type AlertItem struct {
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	Link        string    `json:"link"`
+	Source      string    `json:"source"`
+	Comments    string    `json:"comments,omitempty"`
+	Points      int       `json:"points,omitempty"`
+	PublishedAt time.Time `json:"publishedAt"`
+}
+
+var alertsMu sync.Mutex
+
+// evaluateAlerts checks items against the alert rules in the background, posting
+// each matching item to the rule's webhook once
+func evaluateAlerts(items []Item) {
+	rules := getAlertRules()
+	if len(rules) == 0 || len(items) == 0 {
+		return
+	}
+	go sendAlerts(rules, items)
+}
+
+// sendAlerts delivers alerts for items matching rules that weren't delivered before
+func sendAlerts(rules []AlertRule, items []Item) {
+	alertsMu.Lock()
+	defer alertsMu.Unlock()
+
+	sent := getAlertsSent()
+	now := time.Now()
+	delivered := 0
+
+	for _, rule := range rules {
+		for _, item := range items {
+			key := rule.Name + ":" + item.ID
+			if _, ok := sent[key]; ok || now.Sub(item.PublishedAt) > alertMaxItemAge {
+				continue
+			}
+
+			keywords := matchAlertRule(rule, item)
+			if len(keywords) == 0 {
+				continue
+			}
+
+			if err := deliverAlert(rule, item, keywords); err != nil {
+				log.Printf("Error delivering alert %s for %s: %v", rule.Name, item.Link, err)
+				continue
+			}
+			log.Printf("Delivered alert %s for %s", rule.Name, item.Link)
+			sent[key] = now
+			delivered++
+		}
+	}
+
+	if delivered == 0 {
+		return
+	}
+	for key, sentAt := range sent {
+		if now.Sub(sentAt) > alertSentRetained {
+			delete(sent, key)
+		}
+	}
+	if err := writeJSONFile(alertsSentFile, sent); err != nil {
+		log.Printf("Error storing sent alerts: %v", err)
+	}
+}
+
+// matchAlertRule returns the keywords of rule found in item
+func matchAlertRule(rule AlertRule, item Item) []string {
+	if len(rule.Sources) > 0 && !hasTag(rule.Sources, item.Source) {
+		return nil
+	}
+
+	text := strings.ToLower(item.Title + "\n" + htmlToText(item.Description) + "\n" + strings.Join(item.Tags, " "))
+	var matched []string
+	for _, keyword := range rule.Keywords {
+		if keyword = strings.TrimSpace(keyword); keyword != "" && strings.Contains(text, strings.ToLower(keyword)) {
+			matched = append(matched, keyword)
+		}
+	}
+	return matched
+}
+
+// deliverAlert posts the alert, retrying with exponential backoff on network errors,
+// rate limiting and server errors
+func deliverAlert(rule AlertRule, item Item, keywords []string) error {
+	backoff := alertRetryBackoff
+	var err error
+	for attempt := 1; attempt <= alertMaxAttempts; attempt++ {
+		var retry bool
+		retry, err = postAlert(rule, item, keywords)
+		if err == nil || !retry {
+			return err
+		}
+		if attempt < alertMaxAttempts {
+			log.Printf("Alert %s attempt %d failed, retrying in %s: %v", rule.Name, attempt, backoff, err)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	return err
+}
+
+// postAlert makes a single delivery attempt and reports whether a failure is worth retrying
+func postAlert(rule AlertRule, item Item, keywords []string) (bool, error) {
+	req, err := newAlertRequest(rule, item, keywords)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("User-Agent", "jbhicks.dev dashboard")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+}
+
+// newAlertRequest builds the webhook request in the rule's format
+func newAlertRequest(rule AlertRule, item Item, keywords []string) (*http.Request, error) {
+	switch rule.Format {
+	case "", "json":
+		body, err := json.Marshal(AlertPayload{
+			Rule:     rule.Name,
+			Keywords: keywords,
+			Item: AlertItem{
+				ID:          item.ID,
+				Title:       item.Title,
+				Link:        item.Link,
+				Source:      item.Source,
+				Comments:    item.Comments,
+				Points:      item.Points,
+				PublishedAt: item.PublishedAt,
+			},
+			SentAt: time.Now(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequest("POST", rule.Webhook, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if rule.Secret != "" {
+			mac := hmac.New(sha256.New, []byte(rule.Secret))
+			mac.Write(body)
+			req.Header.Set("X-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		}
+		return req, nil
+
+	case "ntfy":
+		// ntfy takes the message as the body and everything else as headers
+		req, err := http.NewRequest("POST", rule.Webhook, strings.NewReader(item.Title+"\n"+item.Link))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Title", mime.QEncoding.Encode("UTF-8", rule.Name+": "+strings.Join(keywords, ", ")))
+		req.Header.Set("Click", item.Link)
+		req.Header.Set("Tags", "newspaper")
+		return req, nil
+
+	case "slack":
+		body, err := json.Marshal(map[string]string{
+			"text": fmt.Sprintf("*%s* matched %s\n<%s|%s> (%s)",
+				rule.Name, strings.Join(keywords, ", "), item.Link, slackEscape(item.Title), slackEscape(item.Source)),
+		})
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequest("POST", rule.Webhook, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported alert format: %s", rule.Format)
+	}
+}
+
+// slackEscape escapes the characters Slack treats as markup in message text
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// getAlertRules reads the alert rules, skipping rules without a webhook
+func getAlertRules() []AlertRule {
+	var rules []AlertRule
+	if err := readJSONFile(alertRulesFile, &rules); err != nil {
+		return nil
+	}
+
+	var valid []AlertRule
+	for _, rule := range rules {
+		if rule.Webhook == "" || len(rule.Keywords) == 0 {
+			log.Printf("Skipping alert rule %q without webhook or keywords", rule.Name)
+			continue
+		}
+		valid = append(valid, rule)
+	}
+	return valid
+}
+
+// getAlertsSent reads the delivered alerts, keyed by rule name and item ID. Callers must hold alertsMu.
+func getAlertsSent() map[string]time.Time {
+	var sent map[string]time.Time
+	if err := readJSONFile(alertsSentFile, &sent); err != nil || sent == nil {
+		return make(map[string]time.Time)
+	}
+	return sent
+}
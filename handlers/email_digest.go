@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+const (
+	emailDigestFile         = "email-digest.json"
+	defaultDigestInterval   = 24 * time.Hour
+	emailDigestSentRetained = 30 * 24 * time.Hour // How long sent IDs are remembered once they left the caches
+)
+
+// EmailDigestConfig holds the SMTP and schedule settings, read from env vars
+type EmailDigestConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	To       []string
+	Interval time.Duration
+}
+
+// EmailDigestRecord remembers when the last digest went out and what it contained
+type EmailDigestRecord struct {
+	LastSent     time.Time            `json:"lastSent"`
+	SentItemIDs  map[string]time.Time `json:"sentItemIds"`  // News item IDs and when they were sent
+	SentTrackIDs map[string]time.Time `json:"sentTrackIds"` // Track IDs and when they were sent
+}
+
+// EmailDigest is the data rendered by the digest email templates
+type EmailDigest struct {
+	Since time.Time
+	Until time.Time
+	News  []Item
+	Mixes []TrackItem
+}
+
+var emailDigestMu sync.Mutex
+
+// getEmailDigestConfig reads the digest configuration. It returns nil when
+// smtp_host or digest_to aren't set, which disables the email digest.
+func getEmailDigestConfig() *EmailDigestConfig {
+	config := &EmailDigestConfig{
+		Host:     os.Getenv("smtp_host"),
+		Port:     os.Getenv("smtp_port"),
+		Username: os.Getenv("smtp_username"),
+		Password: os.Getenv("smtp_password"),
+		From:     os.Getenv("digest_from"),
+		Interval: defaultDigestInterval,
+	}
+	for _, to := range strings.Split(os.Getenv("digest_to"), ",") {
+		if to = strings.TrimSpace(to); to != "" {
+			config.To = append(config.To, to)
+		}
+	}
+
+	if config.Host == "" || len(config.To) == 0 {
+		return nil
+	}
+	if config.Port == "" {
+		config.Port = "25"
+	}
+	if config.From == "" {
+		config.From = "dashboard@jbhicks.dev"
+	}
+	if interval, err := time.ParseDuration(os.Getenv("digest_interval")); err == nil && interval > 0 {
+		config.Interval = interval
+	}
+	return config
+}
+
+// MaybeSendEmailDigest sends the digest email when it is enabled and the
+// configured interval has passed since the previous one
+func MaybeSendEmailDigest() {
+	config := getEmailDigestConfig()
+	if config == nil {
+		return
+	}
+
+	emailDigestMu.Lock()
+	defer emailDigestMu.Unlock()
+
+	record := getEmailDigestRecord()
+	now := time.Now()
+	if now.Sub(record.LastSent) < config.Interval {
+		return
+	}
+
+	since := record.LastSent
+	if since.IsZero() {
+		since = now.Add(-config.Interval)
+	}
+
+	digest := buildEmailDigest(record, since, now)
+	if len(digest.News) == 0 && len(digest.Mixes) == 0 {
+		log.Printf("Nothing new for the email digest since %s", since.Format(time.RFC3339))
+		return
+	}
+
+	message, err := renderDigestEmail(config, digest)
+	if err != nil {
+		log.Printf("Error rendering email digest: %v", err)
+		return
+	}
+
+	if err := sendMail(config, message); err != nil {
+		log.Printf("Error sending email digest: %v", err)
+		return
+	}
+	log.Printf("Sent email digest with %d news items and %d mixes to %s", len(digest.News), len(digest.Mixes), strings.Join(config.To, ", "))
+
+	record.LastSent = now
+	for _, item := range digest.News {
+		record.SentItemIDs[item.ID] = now
+	}
+	for _, item := range digest.Mixes {
+		record.SentTrackIDs[strconv.Itoa(item.Track.ID)] = now
+	}
+	newsIDs, trackIDs := cachedDigestIDs()
+	pruneSentIDs(record.SentItemIDs, newsIDs, now)
+	pruneSentIDs(record.SentTrackIDs, trackIDs, now)
+	if err := writeJSONFile(emailDigestFile, record); err != nil {
+		log.Printf("Error storing email digest record: %v", err)
+	}
+}
+
+// buildEmailDigest collects the news items and stream mixes that weren't in a previous digest
+func buildEmailDigest(record *EmailDigestRecord, since time.Time, now time.Time) *EmailDigest {
+	digest := &EmailDigest{Since: since, Until: now}
+
+	if news, err := getCachedNews(); err == nil {
+		for _, item := range news.Collection {
+			if _, sent := record.SentItemIDs[item.ID]; !sent && item.PublishedAt.After(since) {
+				digest.News = append(digest.News, item)
+			}
+		}
+	}
+
+	// The stream cache only holds long mixes, so anything unsent there is a new long mix
+	if mixes, err := getCachedMixes("soundcloud-stream"); err == nil {
+		for _, item := range mixes.Collection {
+			if item.Track == nil {
+				continue
+			}
+			if _, sent := record.SentTrackIDs[strconv.Itoa(item.Track.ID)]; sent {
+				continue
+			}
+			// On the first digest only mixes posted within the interval count as new
+			if record.LastSent.IsZero() {
+				createdAt, err := time.Parse(time.RFC3339, item.Track.CreatedAt)
+				if err != nil || createdAt.Before(since) {
+					continue
+				}
+			}
+			digest.Mixes = append(digest.Mixes, item)
+		}
+	}
+
+	return digest
+}
+
+// renderDigestEmail builds a multipart/alternative message with plain text and HTML versions of the digest
+func renderDigestEmail(config *EmailDigestConfig, digest *EmailDigest) ([]byte, error) {
+	textTemplate, err := template.ParseFiles("templates/digest-email.txt")
+	if err != nil {
+		return nil, err
+	}
+	htmlTemplate, err := htmltemplate.ParseFiles("templates/digest-email.html")
+	if err != nil {
+		return nil, err
+	}
+
+	var text, html bytes.Buffer
+	if err := textTemplate.ExecuteTemplate(&text, "digest-email-text", digest); err != nil {
+		return nil, err
+	}
+	if err := htmlTemplate.ExecuteTemplate(&html, "digest-email-html", digest); err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=UTF-8", text.Bytes()},
+		{"text/html; charset=UTF-8", html.Bytes()},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoder := quotedprintable.NewWriter(w)
+		if _, err := encoder.Write(part.content); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	subject := fmt.Sprintf("jbhicks.dev digest: %d news, %d new mixes", len(digest.News), len(digest.Mixes))
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", config.From)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(config.To, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", digest.Until.Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", parts.Boundary())
+	message.Write(body.Bytes())
+	return message.Bytes(), nil
+}
+
+// sendMail delivers message over SMTP, authenticating only when a username is configured
+func sendMail(config *EmailDigestConfig, message []byte) error {
+	if strings.ContainsAny(config.From, "\r\n") {
+		return errors.New("invalid from address")
+	}
+
+	var auth smtp.Auth
+	if config.Username != "" {
+		auth = smtp.PlainAuth("", config.Username, config.Password, config.Host)
+	}
+	return smtp.SendMail(net.JoinHostPort(config.Host, config.Port), auth, config.From, config.To, message)
+}
+
+// getEmailDigestRecord reads the digest record. Callers must hold emailDigestMu.
+func getEmailDigestRecord() *EmailDigestRecord {
+	var record EmailDigestRecord
+	if err := readJSONFile(emailDigestFile, &record); err != nil {
+		record = EmailDigestRecord{}
+	}
+	if record.SentItemIDs == nil {
+		record.SentItemIDs = make(map[string]time.Time)
+	}
+	if record.SentTrackIDs == nil {
+		record.SentTrackIDs = make(map[string]time.Time)
+	}
+	return &record
+}
+
+// cachedDigestIDs returns the IDs of the news items and stream tracks the digest picks from
+func cachedDigestIDs() (map[string]bool, map[string]bool) {
+	newsIDs := make(map[string]bool)
+	if news, err := getCachedNews(); err == nil {
+		for _, item := range news.Collection {
+			newsIDs[item.ID] = true
+		}
+	}
+
+	trackIDs := make(map[string]bool)
+	if mixes, err := getCachedMixes("soundcloud-stream"); err == nil {
+		for _, item := range mixes.Collection {
+			if item.Track != nil {
+				trackIDs[strconv.Itoa(item.Track.ID)] = true
+			}
+		}
+	}
+	return newsIDs, trackIDs
+}
+
+// pruneSentIDs forgets IDs sent longer ago than emailDigestSentRetained. IDs still in
+// cached are kept however old, since forgetting them would send those items again.
+func pruneSentIDs(ids map[string]time.Time, cached map[string]bool, now time.Time) {
+	for id, sentAt := range ids {
+		if !cached[id] && now.Sub(sentAt) > emailDigestSentRetained {
+			delete(ids, id)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const digestWindow = 24 * time.Hour
+
+// digestSection is the news from one source, rendered as one chapter of the digest
+type digestSection struct {
+	ID     string
+	Source string
+	Items  []Item
+}
+
+// HandleGetDigestEPUB handles the GET /digest/today.epub endpoint
+func HandleGetDigestEPUB(c *gin.Context) {
+	log.Printf("[GET] digest epub")
+
+	now := time.Now()
+	epub, err := BuildDigestEPUB(now)
+	if err != nil {
+		log.Printf("Error building digest: %v", err)
+		c.String(http.StatusInternalServerError, "Failed to build digest")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, DigestFilename(now)))
+	c.Data(http.StatusOK, "application/epub+zip", epub)
+}
+
+// DigestFilename returns the default file name for the digest of the given day
+func DigestFilename(now time.Time) string {
+	return "jbhicks-dev-digest-" + now.Format("2006-01-02") + ".epub"
+}
+
+// BuildDigestEPUB builds an EPUB 3 book of the news published in the 24 hours before now,
+// with one chapter per source and the extracted article bodies where available
+func BuildDigestEPUB(now time.Time) ([]byte, error) {
+	news, err := getCachedNews()
+	if err != nil {
+		return nil, err
+	}
+
+	sections := digestSections(news.Collection, now.Add(-digestWindow))
+	title := "jbhicks.dev digest – " + now.Format("Monday, January 2, 2006")
+	sum := sha1.Sum([]byte("jbhicks.dev digest " + now.Format("2006-01-02")))
+	identifier := fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+
+	var buf bytes.Buffer
+	book := zip.NewWriter(&buf)
+
+	// The mimetype entry must come first and be stored uncompressed
+	mimetype, err := book.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := mimetype.Write([]byte("application/epub+zip")); err != nil {
+		return nil, err
+	}
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"META-INF/container.xml", epubContainer},
+		{"OEBPS/content.opf", digestPackage(title, identifier, now, sections)},
+		{"OEBPS/nav.xhtml", digestNav(title, now, sections)},
+		{"OEBPS/toc.ncx", digestNCX(title, identifier, sections)},
+	}
+	for _, section := range sections {
+		files = append(files, struct {
+			name    string
+			content string
+		}{"OEBPS/" + section.ID + ".xhtml", digestChapter(section)})
+	}
+
+	for _, file := range files {
+		w, err := book.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(file.content)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := book.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// digestSections groups the items published after since by source, sources in alphabetical order
+func digestSections(items []Item, since time.Time) []digestSection {
+	bySource := make(map[string][]Item)
+	for _, item := range items {
+		if item.PublishedAt.After(since) {
+			bySource[item.Source] = append(bySource[item.Source], item)
+		}
+	}
+
+	var sources []string
+	for source := range bySource {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	var sections []digestSection
+	for i, source := range sources {
+		sourceItems := bySource[source]
+		sort.SliceStable(sourceItems, func(a, b int) bool {
+			return sourceItems[a].PublishedAt.After(sourceItems[b].PublishedAt)
+		})
+		sections = append(sections, digestSection{
+			ID:     fmt.Sprintf("section-%d", i+1),
+			Source: source,
+			Items:  sourceItems,
+		})
+	}
+	return sections
+}
+
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+// digestPackage renders the OPF package document listing every file in the book
+func digestPackage(title string, identifier string, now time.Time, sections []digestSection) string {
+	var manifest, spine strings.Builder
+	for _, section := range sections {
+		fmt.Fprintf(&manifest, `    <item id="%s" href="%s.xhtml" media-type="application/xhtml+xml"/>`+"\n", section.ID, section.ID)
+		fmt.Fprintf(&spine, `    <itemref idref="%s"/>`+"\n", section.ID)
+	}
+
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="book-id">` + identifier + `</dc:identifier>
+    <dc:title>` + html.EscapeString(xmlText(title)) + `</dc:title>
+    <dc:language>en</dc:language>
+    <dc:creator>jbhicks.dev</dc:creator>
+    <meta property="dcterms:modified">` + now.UTC().Format("2006-01-02T15:04:05Z") + `</meta>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+` + manifest.String() + `  </manifest>
+  <spine toc="ncx">
+    <itemref idref="nav"/>
+` + spine.String() + `  </spine>
+</package>
+`
+}
+
+// digestNav renders the EPUB 3 navigation document, a table of contents grouped by source
+func digestNav(title string, now time.Time, sections []digestSection) string {
+	var toc strings.Builder
+	for _, section := range sections {
+		fmt.Fprintf(&toc, "      <li><a href=\"%s.xhtml\">%s</a>\n        <ol>\n", section.ID, html.EscapeString(xmlText(section.Source)))
+		for i, item := range section.Items {
+			fmt.Fprintf(&toc, "          <li><a href=\"%s.xhtml#item-%d\">%s</a></li>\n", section.ID, i+1, html.EscapeString(xmlText(item.Title)))
+		}
+		toc.WriteString("        </ol>\n      </li>\n")
+	}
+
+	// The toc needs at least one entry, so on quiet days it only points at itself
+	empty := ""
+	if len(sections) == 0 {
+		empty = "  <p>No news in the last 24 hours</p>\n"
+		toc.WriteString("      <li><a href=\"nav.xhtml\">Contents</a></li>\n")
+	}
+
+	return xhtmlDocument(title, `  <h1>`+html.EscapeString(xmlText(title))+`</h1>
+  <p>News from `+now.Add(-digestWindow).Format("Jan 2 15:04")+` to `+now.Format("Jan 2 15:04")+`</p>
+`+empty+`  <nav epub:type="toc" id="toc">
+    <h2>Contents</h2>
+    <ol>
+`+toc.String()+`    </ol>
+  </nav>
+`)
+}
+
+// digestNCX renders the EPUB 2 table of contents, still used by some e-readers
+func digestNCX(title string, identifier string, sections []digestSection) string {
+	var points strings.Builder
+	order := 1
+	for _, section := range sections {
+		fmt.Fprintf(&points, "    <navPoint id=\"nav-%s\" playOrder=\"%d\">\n      <navLabel><text>%s</text></navLabel>\n      <content src=\"%s.xhtml\"/>\n",
+			section.ID, order, html.EscapeString(xmlText(section.Source)), section.ID)
+		order++
+		for i, item := range section.Items {
+			fmt.Fprintf(&points, "      <navPoint id=\"nav-%s-%d\" playOrder=\"%d\">\n        <navLabel><text>%s</text></navLabel>\n        <content src=\"%s.xhtml#item-%d\"/>\n      </navPoint>\n",
+				section.ID, i+1, order, html.EscapeString(xmlText(item.Title)), section.ID, i+1)
+			order++
+		}
+		points.WriteString("    </navPoint>\n")
+	}
+
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">
+  <head>
+    <meta name="dtb:uid" content="` + identifier + `"/>
+  </head>
+  <docTitle><text>` + html.EscapeString(xmlText(title)) + `</text></docTitle>
+  <navMap>
+` + points.String() + `  </navMap>
+</ncx>
+`
+}
+
+// digestChapter renders the articles of one source
+func digestChapter(section digestSection) string {
+	var body strings.Builder
+	fmt.Fprintf(&body, "  <h1>%s</h1>\n", html.EscapeString(xmlText(section.Source)))
+	for i, item := range section.Items {
+		fmt.Fprintf(&body, "  <section id=\"item-%d\">\n    <h2>%s</h2>\n", i+1, html.EscapeString(xmlText(item.Title)))
+		fmt.Fprintf(&body, "    <p><em>%s</em> &#8211; <a href=\"%s\">Original article</a></p>\n",
+			item.PublishedAt.Format("Jan 2, 15:04"), html.EscapeString(xmlText(item.Link)))
+
+		content := item.Description
+		if article := getCachedArticle(item.Link); article != nil && article.Content != "" {
+			content = string(article.Content)
+		} else if item.Content != "" {
+			content = item.Content
+		}
+		body.WriteString(fragmentToXHTML(content, item.Link))
+		body.WriteString("\n  </section>\n")
+	}
+
+	return xhtmlDocument(section.Source, body.String())
+}
+
+// xhtmlDocument wraps body in an XHTML content document
+func xhtmlDocument(title string, body string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="en" xml:lang="en">
+<head>
+  <meta charset="UTF-8"/>
+  <title>` + html.EscapeString(xmlText(title)) + `</title>
+</head>
+<body>
+` + body + `</body>
+</html>
+`
+}
+
+// fragmentToXHTML converts an HTML fragment into well-formed XHTML, keeping only
+// the elements allowed in reader view. Images are dropped since EPUB readers
+// can't load remote resources.
+func fragmentToXHTML(fragment string, pageURL string) string {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		base = &url.URL{}
+	}
+
+	context := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), context)
+	if err != nil {
+		return ""
+	}
+
+	var out strings.Builder
+	for _, n := range nodes {
+		renderXHTML(&out, n, base)
+	}
+	return out.String()
+}
+
+// renderXHTML writes n as XHTML keeping only allowed elements and attributes
+func renderXHTML(out *strings.Builder, n *html.Node, base *url.URL) {
+	switch n.Type {
+	case html.TextNode:
+		out.WriteString(html.EscapeString(xmlText(n.Data)))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	if strippedElements[n.DataAtom] || n.DataAtom == atom.Img {
+		return
+	}
+
+	attrs, allowed := allowedElements[n.DataAtom]
+	if !allowed {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			renderXHTML(out, child, base)
+		}
+		return
+	}
+
+	out.WriteString("<" + n.Data)
+	for _, name := range attrs {
+		value := attr(n, name)
+		if name == "href" {
+			value = resolveURL(base, value)
+		}
+		if value != "" {
+			out.WriteString(" " + name + `="` + html.EscapeString(value) + `"`)
+		}
+	}
+
+	if n.DataAtom == atom.Br || n.DataAtom == atom.Hr {
+		out.WriteString("/>")
+		return
+	}
+	out.WriteString(">")
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		renderXHTML(out, child, base)
+	}
+	out.WriteString("</" + n.Data + ">")
+}
+
+// xmlText drops control characters that aren't allowed in XML documents
+func xmlText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' {
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const eventKeepAlive = 30 * time.Second
+
+// DashboardEvent announces that a cache refresh finished
+type DashboardEvent struct {
+	Name     string // SSE event name, e.g. "news-updated" or "stream-updated"
+	NewItems int    // Items that weren't in the cache before the refresh
+	URL      string // Fragment endpoint to re-fetch the panel from
+	Target   string // ID of the panel element the fragment is swapped into
+	Include  string // Selector of the panel's option controls sent along with the re-fetch, if any
+}
+
+var (
+	eventSubscribersMu sync.Mutex
+	eventSubscribers   = make(map[chan DashboardEvent]bool)
+)
+
+// HandleGetEvents handles the GET /api/events Server-Sent Events endpoint
+func HandleGetEvents(c *gin.Context) {
+	log.Printf("[GET] events")
+
+	events := make(chan DashboardEvent, 8)
+	eventSubscribersMu.Lock()
+	eventSubscribers[events] = true
+	eventSubscribersMu.Unlock()
+
+	defer func() {
+		eventSubscribersMu.Lock()
+		delete(eventSubscribers, events)
+		eventSubscribersMu.Unlock()
+	}()
+
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Writer.Header().Set("X-Accel-Buffering", "no")
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event := <-events:
+			c.SSEvent(event.Name, renderUpdateBanner(event))
+		case <-keepAlive.C:
+			c.SSEvent("ping", "")
+		case <-c.Request.Context().Done():
+			return false
+		}
+		return true
+	})
+}
+
+// publishEvent sends an event to every connected dashboard, dropping it for clients that fall behind
+func publishEvent(event DashboardEvent) {
+	log.Printf("Publishing %s event with %d new items", event.Name, event.NewItems)
+
+	eventSubscribersMu.Lock()
+	defer eventSubscribersMu.Unlock()
+
+	for events := range eventSubscribers {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+// publishCacheEvent announces a refreshed SoundCloud cache, e.g. soundcloud-stream as stream-updated
+func publishCacheEvent(key string, newItems int) {
+	name := strings.TrimPrefix(key, "soundcloud-")
+	event := DashboardEvent{
+		Name:     name + "-updated",
+		NewItems: newItems,
+		URL:      soundcloudCacheURL(key),
+		Target:   name + "-content",
+	}
+	if key == "soundcloud-stream" {
+		// Keep the playlist, heard and filter toggles the stream is shown with
+		event.Include = "#stream-options"
+	}
+	publishEvent(event)
+}
+
+// publishNewsEvent announces a refreshed news cache
+func publishNewsEvent(newItems int) {
+	publishEvent(DashboardEvent{
+		Name:     "news-updated",
+		NewItems: newItems,
+		URL:      "/api/news",
+		Target:   "news-all-content",
+	})
+}
+
+// renderUpdateBanner renders the "N new items" banner sent as the event data.
+// An empty banner clears any banner already shown.
+func renderUpdateBanner(event DashboardEvent) string {
+	if event.NewItems == 0 {
+		return ""
+	}
+
+	tmpl, err := template.ParseFiles("templates/update-banner.html")
+	if err != nil {
+		log.Printf("Error parsing update banner template: %v", err)
+		return ""
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&out, "update-banner", event); err != nil {
+		log.Printf("Error rendering update banner: %v", err)
+		return ""
+	}
+	return out.String()
+}
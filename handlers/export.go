@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportedMixes is the JSON export of a SoundCloud cache. Its schema is our own, so
+// it stays stable when the SoundCloud API changes.
+type ExportedMixes struct {
+	Source     string        `json:"source"`
+	ExportedAt time.Time     `json:"exported_at"`
+	Mixes      []ExportedMix `json:"mixes"`
+}
+
+// ExportedMix is a track or playlist in an export
+type ExportedMix struct {
+	ID         int               `json:"id"`
+	Kind       string            `json:"kind"` // "track" or "playlist"
+	Title      string            `json:"title"`
+	Artist     string            `json:"artist"`
+	URL        string            `json:"url"`
+	ArtworkURL string            `json:"artwork_url,omitempty"`
+	Duration   int               `json:"duration"` // Seconds
+	Genre      string            `json:"genre,omitempty"`
+	CreatedAt  string            `json:"created_at"`
+	Plays      int               `json:"plays"`
+	Likes      int               `json:"likes"`
+	TrackCount int               `json:"track_count,omitempty"` // Playlists only
+	Chapters   []ExportedChapter `json:"chapters,omitempty"`
+}
+
+// ExportedChapter is an entry of a mix's tracklist in an export
+type ExportedChapter struct {
+	Number int    `json:"number"`
+	Start  *int   `json:"start,omitempty"` // Seconds into the mix, omitted for tracklists without times
+	Artist string `json:"artist,omitempty"`
+	Title  string `json:"title"`
+	URL    string `json:"url,omitempty"`
+}
+
+// xspfPlaylist is an XSPF playlist, see https://xspf.org/spec
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"playlist"`
+	Version string      `xml:"version,attr"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	Title   string      `xml:"title"`
+	Date    string      `xml:"date"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location string `xml:"location"`
+	Title    string `xml:"title"`
+	Creator  string `xml:"creator"`
+	Duration int    `xml:"duration,omitempty"` // Milliseconds
+	Image    string `xml:"image,omitempty"`
+	Info     string `xml:"info"`
+}
+
+// HandleGetSoundcloudExport handles the GET /api/soundcloud/export/:key/:format endpoint,
+// exporting a cache as filtered for its tab in the m3u8, xspf, csv or json format
+func HandleGetSoundcloudExport(c *gin.Context) {
+	key := c.Param("key")
+	format := c.Param("format")
+	log.Printf("[GET] soundcloud export %s %s", key, format)
+
+	known := false
+	for _, cacheKey := range soundcloudCacheKeys() {
+		if cacheKey == key {
+			known = true
+			break
+		}
+	}
+	if !known {
+		c.String(http.StatusNotFound, "Unknown cache")
+		return
+	}
+
+	mixes := exportMixes(getFilteredMixes(c, key, key == "soundcloud-stream").Collection)
+	filename := key + "." + format
+
+	switch format {
+	case "m3u8":
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		c.Data(http.StatusOK, "audio/x-mpegurl; charset=utf-8", renderM3U(key, mixes))
+	case "xspf":
+		body, err := renderXSPF(key, mixes)
+		if err != nil {
+			log.Printf("Error rendering XSPF export of %s: %v", key, err)
+			c.String(http.StatusInternalServerError, "Failed to export")
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		c.Data(http.StatusOK, "application/xspf+xml", body)
+	case "csv":
+		body, err := renderCSV(mixes)
+		if err != nil {
+			log.Printf("Error rendering CSV export of %s: %v", key, err)
+			c.String(http.StatusInternalServerError, "Failed to export")
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", body)
+	case "json":
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		c.JSON(http.StatusOK, ExportedMixes{Source: key, ExportedAt: time.Now(), Mixes: mixes})
+	default:
+		c.String(http.StatusBadRequest, "Unsupported export format")
+	}
+}
+
+// exportMixes converts stream items to the export schema
+func exportMixes(items []TrackItem) []ExportedMix {
+	mixes := []ExportedMix{}
+	for _, item := range items {
+		switch {
+		case item.Track != nil:
+			t := item.Track
+			mixes = append(mixes, ExportedMix{
+				ID:         t.ID,
+				Kind:       "track",
+				Title:      t.Title,
+				Artist:     t.User.Username,
+				URL:        t.PermalinkURL,
+				ArtworkURL: t.ArtworkURL,
+				Duration:   t.Duration / 1000,
+				Genre:      t.Genre,
+				CreatedAt:  t.CreatedAt,
+				Plays:      t.PlaybackCount,
+				Likes:      t.LikesCount,
+				Chapters:   exportChapters(t.Chapters),
+			})
+		case item.Playlist != nil:
+			p := item.Playlist
+			mixes = append(mixes, ExportedMix{
+				ID:         p.ID,
+				Kind:       "playlist",
+				Title:      p.Title,
+				Artist:     p.User.Username,
+				URL:        p.PermalinkURL,
+				ArtworkURL: p.Artwork(),
+				Duration:   p.Duration / 1000,
+				Genre:      p.Genre,
+				CreatedAt:  p.CreatedAt,
+				Likes:      p.LikesCount,
+				TrackCount: p.TrackCount,
+			})
+		}
+	}
+	return mixes
+}
+
+// exportChapters converts a parsed tracklist to the export schema
+func exportChapters(chapters []Chapter) []ExportedChapter {
+	var exported []ExportedChapter
+	for _, chapter := range chapters {
+		entry := ExportedChapter{
+			Number: chapter.Number,
+			Artist: chapter.Artist,
+			Title:  chapter.Title,
+			URL:    chapter.URL,
+		}
+		if chapter.Start >= 0 {
+			start := chapter.Start
+			entry.Start = &start
+		}
+		exported = append(exported, entry)
+	}
+	return exported
+}
+
+// renderM3U renders mixes as an extended M3U playlist
+func renderM3U(title string, mixes []ExportedMix) []byte {
+	var b bytes.Buffer
+	b.WriteString("#EXTM3U\n")
+	fmt.Fprintf(&b, "#PLAYLIST:%s\n", title)
+	for _, mix := range mixes {
+		fmt.Fprintf(&b, "#EXTINF:%d,%s - %s\n", mix.Duration, m3uText(mix.Artist), m3uText(mix.Title))
+		if mix.ArtworkURL != "" {
+			fmt.Fprintf(&b, "#EXTIMG:%s\n", mix.ArtworkURL)
+		}
+		b.WriteString(mix.URL + "\n")
+	}
+	return b.Bytes()
+}
+
+// m3uText keeps line breaks in titles from starting new M3U lines
+func m3uText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// renderXSPF renders mixes as an XSPF playlist
+func renderXSPF(title string, mixes []ExportedMix) ([]byte, error) {
+	playlist := xspfPlaylist{
+		Version: "1",
+		XMLNS:   "http://xspf.org/ns/0/",
+		Title:   title,
+		Date:    time.Now().Format(time.RFC3339),
+	}
+	for _, mix := range mixes {
+		playlist.Tracks = append(playlist.Tracks, xspfTrack{
+			Location: mix.URL,
+			Title:    mix.Title,
+			Creator:  mix.Artist,
+			Duration: mix.Duration * 1000,
+			Image:    mix.ArtworkURL,
+			Info:     mix.URL,
+		})
+	}
+
+	body, err := xml.MarshalIndent(playlist, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// renderCSV renders mixes as CSV with a header row
+func renderCSV(mixes []ExportedMix) ([]byte, error) {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	w.Write([]string{"id", "kind", "title", "artist", "url", "duration_seconds", "duration", "genre", "created_at", "plays", "likes", "track_count", "artwork_url"})
+	for _, mix := range mixes {
+		w.Write([]string{
+			strconv.Itoa(mix.ID),
+			mix.Kind,
+			csvText(mix.Title),
+			csvText(mix.Artist),
+			csvText(mix.URL),
+			strconv.Itoa(mix.Duration),
+			strings.Join(strings.Fields(setDurationText(mix.Duration*1000)), " "),
+			csvText(mix.Genre),
+			csvText(mix.CreatedAt),
+			strconv.Itoa(mix.Plays),
+			strconv.Itoa(mix.Likes),
+			strconv.Itoa(mix.TrackCount),
+			csvText(mix.ArtworkURL),
+		})
+	}
+	w.Flush()
+	return b.Bytes(), w.Error()
+}
+
+// csvText keeps spreadsheets from evaluating a cell that starts like a formula
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
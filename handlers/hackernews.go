@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHNAPIBase = "https://hacker-news.firebaseio.com"
+	hnFetchLimit     = 8 // Concurrent requests to the HN API
+)
+
+// HNItem is the subset of the Hacker News API item we use
+type HNItem struct {
+	ID          int    `json:"id"`
+	By          string `json:"by"`
+	Score       int    `json:"score"`
+	Descendants int    `json:"descendants"`
+}
+
+// hnAPIBase returns the HN API base URL, overridable with the hn_api_base env var
+func hnAPIBase() string {
+	if base := os.Getenv("hn_api_base"); base != "" {
+		return strings.TrimRight(base, "/")
+	}
+	return defaultHNAPIBase
+}
+
+// hnItemID extracts the story id from a news.ycombinator.com/item?id= comments URL
+func hnItemID(commentsURL string) int {
+	parsed, err := url.Parse(commentsURL)
+	if err != nil || !strings.HasSuffix(parsed.Host, "ycombinator.com") || parsed.Path != "/item" {
+		return 0
+	}
+
+	id, err := strconv.Atoi(parsed.Query().Get("id"))
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// enrichHackerNews fills points, comment counts and authors for items linking to HN discussions
+func enrichHackerNews(items []Item) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	base := hnAPIBase()
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, hnFetchLimit)
+	enriched := 0
+	var mu sync.Mutex
+
+	for i := range items {
+		id := hnItemID(items[i].Comments)
+		if id == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(item *Item, id int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			hnItem, err := fetchHNItem(client, base, id)
+			if err != nil {
+				log.Printf("Error fetching HN item %d: %v", id, err)
+				return
+			}
+
+			item.Points = hnItem.Score
+			item.HasPoints = true
+			item.CommentCount = hnItem.Descendants
+			item.Author = hnItem.By
+
+			mu.Lock()
+			enriched++
+			mu.Unlock()
+		}(&items[i], id)
+	}
+	wg.Wait()
+
+	if enriched > 0 {
+		log.Printf("Enriched %d items from the HN API", enriched)
+	}
+}
+
+// fetchHNItem retrieves a single item from the HN API
+func fetchHNItem(client *http.Client, base string, id int) (*HNItem, error) {
+	resp, err := client.Get(fmt.Sprintf("%s/v0/item/%d.json", base, id))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var hnItem HNItem
+	if err := json.NewDecoder(resp.Body).Decode(&hnItem); err != nil {
+		return nil, err
+	}
+	if hnItem.ID == 0 {
+		return nil, fmt.Errorf("item %d not found", id)
+	}
+	return &hnItem, nil
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	listeningHistoryFile = "listening-history.json"
+	heardThreshold       = 0.9       // Progress from which a mix counts as heard
+	maxPingSeconds       = 60        // Longest gap between progress pings counted as listening
+	listenSessionGap     = time.Hour // Pause after which listening to a mix again starts a new session
+)
+
+// ListeningHistory records which mixes were played and how far
+type ListeningHistory struct {
+	Tracks map[string]*HeardTrack `json:"tracks"` // Keyed by track ID
+	Events []ListenEvent          `json:"events"`
+}
+
+// HeardTrack sums up the plays of one track, keeping the metadata so the history
+// outlives the SoundCloud caches
+type HeardTrack struct {
+	TrackID        int       `json:"trackId"`
+	Title          string    `json:"title"`
+	Artist         string    `json:"artist"`
+	Genre          string    `json:"genre"`
+	Duration       int       `json:"duration"`     // Milliseconds
+	Plays          int       `json:"plays"`        // Listening sessions, see listenSessionGap
+	Progress       float64   `json:"progress"`     // Furthest position reached, from 0 to 1
+	LastPosition   float64   `json:"lastPosition"` // Seconds, to measure time listened between pings
+	LastPlayedAt   time.Time `json:"lastPlayedAt"`
+	LastListenedAt time.Time `json:"lastListenedAt"` // Last open, play or ping, to tell sessions apart
+}
+
+// ListenEvent is a single entry in the listening history. The progress pings of one
+// listening session are merged into a single "progress" event, so the history grows
+// by session rather than by ping.
+type ListenEvent struct {
+	TrackID int       `json:"trackId"`
+	Kind    string    `json:"kind"` // "open" for the card link, "play" when the player starts, "progress" for player pings
+	At      time.Time `json:"at"`
+	Until   time.Time `json:"until,omitempty"`   // Last ping of a progress session
+	Seconds float64   `json:"seconds,omitempty"` // Time listened during the session
+}
+
+var listeningHistoryMu sync.Mutex
+
+// HandleGetMixRedirect handles the GET /go/mix/:id endpoint behind the mix card links,
+// recording the play before redirecting to SoundCloud
+func HandleGetMixRedirect(c *gin.Context) {
+	trackID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid track id")
+		return
+	}
+	log.Printf("[GET] go mix %d", trackID)
+
+	track, err := findTrack(trackID)
+	if err != nil {
+		c.String(http.StatusNotFound, "Track not found")
+		return
+	}
+
+	recordListen(trackID, track, "open", 0, 0)
+	c.Redirect(http.StatusFound, track.PermalinkURL)
+}
+
+// recordListen adds an event to the listening history. Track may be nil for
+// progress pings of tracks that are no longer cached. Opening or starting a mix
+// counts as a play only when it starts a new session, so resumes, reloads and
+// retries don't add up.
+func recordListen(trackID int, track *Track, kind string, position float64, duration float64) {
+	listeningHistoryMu.Lock()
+	defer listeningHistoryMu.Unlock()
+
+	history := getListeningHistory()
+	addListen(history, trackID, track, kind, position, duration, time.Now())
+	if err := writeJSONFile(listeningHistoryFile, history); err != nil {
+		log.Printf("Error storing listening history: %v", err)
+	}
+}
+
+// addListen applies an open, play or progress ping at now to history
+func addListen(history *ListeningHistory, trackID int, track *Track, kind string, position float64, duration float64, now time.Time) {
+	id := strconv.Itoa(trackID)
+	heard := history.Tracks[id]
+	if heard == nil {
+		heard = &HeardTrack{TrackID: trackID}
+		history.Tracks[id] = heard
+	}
+	if track != nil {
+		heard.Title = track.Title
+		heard.Artist = track.User.Username
+		heard.Genre = track.Genre
+		heard.Duration = track.Duration
+	}
+
+	newSession := now.Sub(heard.LastListenedAt) > listenSessionGap
+	heard.LastListenedAt = now
+	event := ListenEvent{TrackID: trackID, Kind: kind, At: now}
+	switch kind {
+	case "open", "play":
+		heard.LastPosition = position
+		if newSession {
+			heard.Plays++
+			heard.LastPlayedAt = now
+		}
+	case "progress":
+		listened := position - heard.LastPosition
+		heard.LastPosition = position
+		if listened <= 0 || listened > maxPingSeconds {
+			// Seeks and paused gaps aren't listening time
+			break
+		}
+		event.Seconds = listened
+		if duration <= 0 {
+			duration = float64(heard.Duration) / 1000
+		}
+		if duration > 0 && position/duration > heard.Progress {
+			heard.Progress = position / duration
+			if heard.Progress > 1 {
+				heard.Progress = 1
+			}
+		}
+	}
+	switch {
+	case kind != "progress" && newSession:
+		history.Events = append(history.Events, event)
+	case event.Seconds > 0:
+		addListenedSeconds(history, event)
+	}
+}
+
+// addListenedSeconds adds a progress ping to the history, extending the session of
+// the track when it is the latest event
+func addListenedSeconds(history *ListeningHistory, ping ListenEvent) {
+	if n := len(history.Events); n > 0 {
+		last := &history.Events[n-1]
+		if last.Kind == "progress" && last.TrackID == ping.TrackID && ping.At.Sub(last.Until) <= listenSessionGap {
+			last.Until = ping.At
+			last.Seconds += ping.Seconds
+			return
+		}
+	}
+	ping.Until = ping.At
+	history.Events = append(history.Events, ping)
+}
+
+// markHeard sets the heard badges of the tracks in items
+func markHeard(items []TrackItem) {
+	listeningHistoryMu.Lock()
+	history := getListeningHistory()
+	listeningHistoryMu.Unlock()
+
+	for _, item := range items {
+		if item.Track == nil {
+			continue
+		}
+		heard := history.Tracks[strconv.Itoa(item.Track.ID)]
+		if heard == nil {
+			continue
+		}
+		item.Track.Opened = heard.Plays > 0
+		item.Track.Heard = heard.Progress >= heardThreshold
+		item.Track.HeardPercent = int(heard.Progress * 100)
+	}
+}
+
+// withoutHeard drops the fully heard tracks from items, which must have been marked
+func withoutHeard(items []TrackItem) []TrackItem {
+	var unheard []TrackItem
+	for _, item := range items {
+		if item.Track == nil || !item.Track.Heard {
+			unheard = append(unheard, item)
+		}
+	}
+	return unheard
+}
+
+// getListeningHistory reads the listening history. Callers must hold listeningHistoryMu.
+func getListeningHistory() *ListeningHistory {
+	var history ListeningHistory
+	if err := readJSONFile(listeningHistoryFile, &history); err != nil {
+		history = ListeningHistory{}
+	}
+	if history.Tracks == nil {
+		history.Tracks = make(map[string]*HeardTrack)
+	}
+	return &history
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// listenStep is one call to addListen, at offset from the start of the test
+type listenStep struct {
+	trackID  int
+	kind     string
+	position float64
+	offset   time.Duration
+}
+
+func TestAddListen(t *testing.T) {
+	const a, b = 1, 2
+
+	tests := []struct {
+		name       string
+		steps      []listenStep
+		wantPlays  map[int]int
+		wantEvents []string // "kind:trackID" or "progress:trackID:seconds"
+	}{
+		{
+			name: "pings merge into one session",
+			steps: []listenStep{
+				{a, "play", 0, 0},
+				{a, "progress", 15, 15 * time.Second},
+				{a, "progress", 30, 30 * time.Second},
+				{a, "progress", 45, 45 * time.Second},
+			},
+			wantPlays:  map[int]int{a: 1},
+			wantEvents: []string{"play:1", "progress:1:45"},
+		},
+		{
+			name: "resuming within the session is not a new play",
+			steps: []listenStep{
+				{a, "play", 0, 0},
+				{a, "progress", 15, 15 * time.Second},
+				{a, "play", 15, 20 * time.Minute},
+				{a, "open", 0, 30 * time.Minute},
+			},
+			wantPlays:  map[int]int{a: 1},
+			wantEvents: []string{"play:1", "progress:1:15"},
+		},
+		{
+			name: "playing again after the session gap is a new play",
+			steps: []listenStep{
+				{a, "play", 0, 0},
+				{a, "play", 0, 2 * time.Hour},
+			},
+			wantPlays:  map[int]int{a: 2},
+			wantEvents: []string{"play:1", "play:1"},
+		},
+		{
+			name: "seeks are not listening time",
+			steps: []listenStep{
+				{a, "play", 0, 0},
+				{a, "progress", 15, 15 * time.Second},
+				{a, "progress", 600, 30 * time.Second},
+				{a, "progress", 615, 45 * time.Second},
+				{a, "progress", 300, time.Minute},
+			},
+			wantPlays:  map[int]int{a: 1},
+			wantEvents: []string{"play:1", "progress:1:30"},
+		},
+		{
+			name: "another track splits the progress session",
+			steps: []listenStep{
+				{a, "play", 0, 0},
+				{a, "progress", 15, 15 * time.Second},
+				{b, "progress", 15, 20 * time.Second},
+				{a, "progress", 30, 30 * time.Second},
+			},
+			wantPlays:  map[int]int{a: 1, b: 0},
+			wantEvents: []string{"play:1", "progress:1:15", "progress:2:15", "progress:1:15"},
+		},
+		{
+			name: "pings after a long pause start a new progress session",
+			steps: []listenStep{
+				{a, "play", 0, 0},
+				{a, "progress", 15, 15 * time.Second},
+				{a, "progress", 30, 2 * time.Hour},
+			},
+			wantPlays:  map[int]int{a: 1},
+			wantEvents: []string{"play:1", "progress:1:15", "progress:1:15"},
+		},
+	}
+
+	start := time.Date(2026, 10, 19, 20, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := &ListeningHistory{Tracks: make(map[string]*HeardTrack)}
+			for _, step := range tt.steps {
+				addListen(history, step.trackID, nil, step.kind, step.position, 3600, start.Add(step.offset))
+			}
+
+			plays := make(map[int]int)
+			for _, heard := range history.Tracks {
+				plays[heard.TrackID] = heard.Plays
+			}
+			if !reflect.DeepEqual(plays, tt.wantPlays) {
+				t.Errorf("plays = %v, want %v", plays, tt.wantPlays)
+			}
+
+			var events []string
+			for _, event := range history.Events {
+				if event.Kind == "progress" {
+					events = append(events, fmt.Sprintf("progress:%d:%g", event.TrackID, event.Seconds))
+				} else {
+					events = append(events, fmt.Sprintf("%s:%d", event.Kind, event.TrackID))
+				}
+			}
+			if !reflect.DeepEqual(events, tt.wantEvents) {
+				t.Errorf("events = %v, want %v", events, tt.wantEvents)
+			}
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"image"
+	"image/draw"
+	_ "image/gif" // Registers the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	imageCacheDir    = "image-cache"
+	imageMaxBytes    = 10 << 20 // Largest source image fetched
+	imageMaxPixels   = 25000000 // Largest source image decoded, against decompression bombs
+	imageJPEGQuality = 85
+)
+
+// imageKeyFile holds the signing key generated when img_proxy_secret isn't set
+const imageKeyFile = "image-proxy.key"
+
+var (
+	// Widths the proxy resizes to, so the cache holds a few variants per image. Requested
+	// widths are rounded up to the next one, with room for high density screens.
+	imageWidths = []int{64, 96, 200, 320, 500, 800}
+
+	// SoundCloud artwork and avatar URLs name their size, e.g. -large for 100x100
+	soundcloudImageSize = regexp.MustCompile(`-(large|t\d+x\d+|crop|badge|small|tiny|mini)\.(jpg|png)$`)
+
+	imageSecret []byte // Key the proxied URLs are signed with, see InitImageProxy
+)
+
+// InitImageProxy sets the key image URLs are signed with. The proxy responses are
+// cached for a year, so the key must stay the same across restarts: it comes from
+// img_proxy_secret, or else from a key generated once and kept in imageKeyFile.
+func InitImageProxy() error {
+	if secret := os.Getenv("img_proxy_secret"); secret != "" {
+		imageSecret = []byte(secret)
+		return nil
+	}
+
+	log.Printf("Warning: img_proxy_secret is not set, signing image URLs with the key in %s", imageKeyFile)
+	if key, err := os.ReadFile(imageKeyFile); err == nil && len(key) > 0 {
+		imageSecret = key
+		return nil
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	imageSecret = []byte(hex.EncodeToString(key))
+	return os.WriteFile(imageKeyFile, imageSecret, 0600)
+}
+
+// TemplateFuncs returns the functions available in the HTML templates
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"imageURL": ImageURL,
+	}
+}
+
+// ImageURL returns the proxied URL of an image at the given width. Local and empty
+// URLs are returned unchanged.
+func ImageURL(src string, width int) string {
+	if !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://") {
+		return src
+	}
+	query := url.Values{}
+	query.Set("url", src)
+	query.Set("w", strconv.Itoa(width))
+	query.Set("s", signImageURL(src))
+	return "/img?" + query.Encode()
+}
+
+// HandleGetImage handles the GET /img endpoint, serving a signed source image resized
+// to the requested width from the disk cache
+func HandleGetImage(c *gin.Context) {
+	src := c.Query("url")
+	if !hmac.Equal([]byte(c.Query("s")), []byte(signImageURL(src))) {
+		c.String(http.StatusForbidden, "Invalid signature")
+		return
+	}
+	width := imageWidth(c.Query("w"))
+
+	data, err := getImage(src, width)
+	if err != nil {
+		log.Printf("Error proxying image %s: %v", src, err)
+		c.Redirect(http.StatusFound, "/static/placeholder.svg")
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Data(http.StatusOK, http.DetectContentType(data), data)
+}
+
+// signImageURL returns the signature of a source URL, so the proxy only fetches
+// images our own pages link
+func signImageURL(src string) string {
+	mac := hmac.New(sha256.New, imageSecret)
+	mac.Write([]byte(src))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// imageWidth rounds a requested width up to one of the proxy widths, or returns 0
+// for the source size
+func imageWidth(s string) int {
+	requested, err := strconv.Atoi(s)
+	if err != nil || requested <= 0 {
+		return 0
+	}
+	for _, width := range imageWidths {
+		if requested <= width {
+			return width
+		}
+	}
+	return imageWidths[len(imageWidths)-1]
+}
+
+// getImage returns the image at src resized to width, from the cache if it is there
+func getImage(src string, width int) ([]byte, error) {
+	path := imageCachePath(src, width)
+	if data, err := os.ReadFile(path); err == nil {
+		return data, nil
+	}
+
+	originalPath := imageCachePath(src, 0)
+	original, err := os.ReadFile(originalPath)
+	if err != nil {
+		if original, err = fetchImage(src); err != nil {
+			return nil, err
+		}
+		if err := writeImageCache(originalPath, original); err != nil {
+			log.Printf("Error caching image %s: %v", src, err)
+		}
+	}
+	if width == 0 {
+		return original, nil
+	}
+
+	resized, err := resizeImage(original, width)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeImageCache(path, resized); err != nil {
+		log.Printf("Error caching image %s at %d: %v", src, width, err)
+	}
+	return resized, nil
+}
+
+// fetchImage downloads an image, preferring SoundCloud's 500x500 variant to their
+// small default so resizing has detail to work with
+func fetchImage(src string) ([]byte, error) {
+	if u, err := url.Parse(src); err == nil && strings.HasSuffix(u.Hostname(), "sndcdn.com") {
+		src = soundcloudImageSize.ReplaceAllString(src, "-t500x500.$2")
+	}
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Get(src)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, imageMaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > imageMaxBytes {
+		return nil, errors.New("image too large")
+	}
+	// Sniffed rather than trusting the header, which also keeps SVG and HTML from being served as ours
+	if contentType := http.DetectContentType(data); !strings.HasPrefix(contentType, "image/") {
+		return nil, fmt.Errorf("not an image: %s", contentType)
+	}
+	return data, nil
+}
+
+// resizeImage scales an image down to width, keeping the aspect ratio. JPEGs stay
+// JPEGs and everything else becomes PNG to keep transparency. Images no wider than
+// width are returned unchanged.
+func resizeImage(data []byte, width int) ([]byte, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		// Formats the standard library can't decode, like WebP, are served as they are
+		return data, nil
+	}
+	if config.Width <= width {
+		return data, nil
+	}
+	if config.Width*config.Height > imageMaxPixels {
+		return nil, errors.New("image has too many pixels")
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	height := config.Height * width / config.Width
+	if height < 1 {
+		height = 1
+	}
+	dst := downscale(src, width, height)
+
+	var b bytes.Buffer
+	if format == "jpeg" {
+		err = jpeg.Encode(&b, dst, &jpeg.Options{Quality: imageJPEGQuality})
+	} else {
+		err = png.Encode(&b, dst)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// downscale shrinks src to width x height by averaging the source pixels each
+// destination pixel covers
+func downscale(src image.Image, width int, height int) *image.RGBA {
+	bounds := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := y * srcHeight / height
+		y1 := (y + 1) * srcHeight / height
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0 := x * srcWidth / width
+			x1 := (x + 1) * srcWidth / width
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += int(p[0])
+					g += int(p[1])
+					b += int(p[2])
+					a += int(p[3])
+					n++
+				}
+			}
+
+			p := dst.Pix[y*dst.Stride+x*4 : y*dst.Stride+x*4+4]
+			p[0] = uint8(r / n)
+			p[1] = uint8(g / n)
+			p[2] = uint8(b / n)
+			p[3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// imageCachePath returns the cache file of an image at a width, 0 being the source
+func imageCachePath(src string, width int) string {
+	sum := sha256.Sum256([]byte(src))
+	return filepath.Join(imageCacheDir, fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), width))
+}
+
+// writeImageCache writes a cache file through a temporary file, so concurrent
+// requests never read a partial image
+func writeImageCache(path string, data []byte) error {
+	if err := os.MkdirAll(imageCacheDir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(imageCacheDir, "tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
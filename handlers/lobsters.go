@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// LobstersStory is a story from the Lobsters JSON endpoints, e.g. /hottest.json or /t/go.json
+type LobstersStory struct {
+	ShortID       string          `json:"short_id"`
+	CreatedAt     string          `json:"created_at"`
+	Title         string          `json:"title"`
+	URL           string          `json:"url"`
+	Score         int             `json:"score"`
+	CommentCount  int             `json:"comment_count"`
+	Description   string          `json:"description"`
+	CommentsURL   string          `json:"comments_url"`
+	SubmitterUser json.RawMessage `json:"submitter_user"`
+	Tags          []string        `json:"tags"`
+}
+
+// fetchLobstersStories retrieves stories from a Lobsters JSON endpoint
+func fetchLobstersStories(url string, sourceName string) ([]Item, error) {
+	log.Printf("Fetching Lobsters stories from %s", url)
+
+	var stories []LobstersStory
+	if err := fetchJSON(url, &stories); err != nil {
+		return nil, err
+	}
+
+	var items []Item
+	for _, story := range stories {
+		publishedAt, err := time.Parse(time.RFC3339, story.CreatedAt)
+		if err != nil {
+			log.Printf("Error parsing date %s: %v", story.CreatedAt, err)
+			publishedAt = time.Now()
+		}
+
+		link := story.URL
+		if link == "" {
+			// Text posts link to their own discussion
+			link = story.CommentsURL
+		}
+
+		items = append(items, Item{
+			ID:           itemID("lobsters:"+story.ShortID, link),
+			Title:        story.Title,
+			Link:         link,
+			Description:  story.Description,
+			Comments:     story.CommentsURL,
+			Source:       sourceName,
+			PublishedAt:  publishedAt,
+			TimePassed:   formatTimePassed(publishedAt),
+			Points:       story.Score,
+			HasPoints:    true,
+			CommentCount: story.CommentCount,
+			Author:       lobstersSubmitter(story.SubmitterUser),
+			Tags:         story.Tags,
+		})
+	}
+
+	log.Printf("Fetched %d items from %s", len(items), sourceName)
+	return items, nil
+}
+
+// lobstersSubmitter reads submitter_user, which is a username in newer
+// versions of the API and a user object in older ones
+func lobstersSubmitter(raw json.RawMessage) string {
+	var username string
+	if err := json.Unmarshal(raw, &username); err == nil {
+		return username
+	}
+
+	var user struct {
+		Username string `json:"username"`
+	}
+	if err := json.Unmarshal(raw, &user); err == nil {
+		return user.Username
+	}
+	return ""
+}
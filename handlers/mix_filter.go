@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultMixFilter keeps tracks longer than ~30m, used for filtered caches without a configured filter
+var defaultMixFilter = MixFilter{MinDuration: "29m10s"}
+
+// MixFilter declares which tracks a cache keeps. Durations are Go durations like "30m".
+// Empty fields don't filter.
+type MixFilter struct {
+	MinDuration    string   `json:"minDuration"`
+	MaxDuration    string   `json:"maxDuration"`
+	Genres         []string `json:"genres"` // Only these genres when set
+	ExcludeGenres  []string `json:"excludeGenres"`
+	Artists        []string `json:"artists"`       // Only tracks uploaded by these usernames or permalinks when set
+	BlockArtists   []string `json:"blockArtists"`  // Drops tracks uploaded or reposted by these
+	TitleKeywords  []string `json:"titleKeywords"` // Title must contain one of these when set
+	ExcludeTitle   []string `json:"excludeTitle"`  // Title must contain none of these
+	ExcludeReposts bool     `json:"excludeReposts"`
+	MinPlays       int      `json:"minPlays"`
+	MinLikes       int      `json:"minLikes"`
+	MaxAge         string   `json:"maxAge"` // Oldest upload to keep
+}
+
+// mixFilterFromQuery builds a filter from the ad-hoc query parameters of a mixes
+// request. It returns nil when none are set.
+func mixFilterFromQuery(c *gin.Context) *MixFilter {
+	filter := MixFilter{
+		MinDuration:    c.Query("min_duration"),
+		MaxDuration:    c.Query("max_duration"),
+		Genres:         queryList(c, "genre"),
+		ExcludeGenres:  queryList(c, "exclude_genre"),
+		Artists:        queryList(c, "artist"),
+		BlockArtists:   queryList(c, "block_artist"),
+		TitleKeywords:  queryList(c, "title"),
+		ExcludeTitle:   queryList(c, "exclude_title"),
+		ExcludeReposts: c.Query("reposts") == "false",
+		MaxAge:         c.Query("max_age"),
+	}
+	filter.MinPlays, _ = strconv.Atoi(c.Query("min_plays"))
+	filter.MinLikes, _ = strconv.Atoi(c.Query("min_likes"))
+
+	if reflect.DeepEqual(filter, MixFilter{}) {
+		return nil
+	}
+	return &filter
+}
+
+// queryList returns the values of a repeatable, comma separated query parameter
+func queryList(c *gin.Context, name string) []string {
+	var values []string
+	for _, value := range c.QueryArray(name) {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+// apply returns the items whose track or playlist passes the filter
+func (f MixFilter) apply(items []TrackItem) []TrackItem {
+	minDuration := parseFilterDuration(f.MinDuration)
+	maxDuration := parseFilterDuration(f.MaxDuration)
+	var oldest time.Time
+	if maxAge := parseFilterDuration(f.MaxAge); maxAge > 0 {
+		oldest = time.Now().Add(-maxAge)
+	}
+
+	var kept []TrackItem
+	for _, item := range items {
+		mix, ok := filterFieldsOf(item)
+		if !ok {
+			continue
+		}
+
+		if (minDuration > 0 && mix.duration <= minDuration) || (maxDuration > 0 && mix.duration > maxDuration) {
+			continue
+		}
+		if len(f.Genres) > 0 && !containsFold(f.Genres, mix.genre) || containsFold(f.ExcludeGenres, mix.genre) {
+			continue
+		}
+		if len(f.Artists) > 0 && !matchesArtist(f.Artists, mix.user) {
+			continue
+		}
+		if matchesArtist(f.BlockArtists, mix.user) || matchesArtist(f.BlockArtists, item.User) {
+			continue
+		}
+		if len(f.TitleKeywords) > 0 && !titleContains(mix.title, f.TitleKeywords) || titleContains(mix.title, f.ExcludeTitle) {
+			continue
+		}
+		if f.ExcludeReposts && strings.HasSuffix(item.Type, "-repost") {
+			continue
+		}
+		if mix.likes < f.MinLikes || (!mix.playlist && mix.plays < f.MinPlays) {
+			continue
+		}
+		if !oldest.IsZero() {
+			if createdAt, err := time.Parse(time.RFC3339, mix.createdAt); err == nil && createdAt.Before(oldest) {
+				continue
+			}
+		}
+
+		kept = append(kept, item)
+	}
+	return kept
+}
+
+// filterFields are the fields of a track or playlist the filter looks at
+type filterFields struct {
+	duration  time.Duration
+	genre     string
+	user      User
+	title     string
+	plays     int // Not reported for playlists
+	likes     int
+	createdAt string
+	playlist  bool
+}
+
+// filterFieldsOf returns the filter fields of the track or playlist of item
+func filterFieldsOf(item TrackItem) (filterFields, bool) {
+	switch {
+	case item.Track != nil:
+		return filterFields{
+			duration:  time.Duration(item.Track.Duration) * time.Millisecond,
+			genre:     item.Track.Genre,
+			user:      item.Track.User,
+			title:     item.Track.Title,
+			plays:     item.Track.PlaybackCount,
+			likes:     item.Track.LikesCount,
+			createdAt: item.Track.CreatedAt,
+		}, true
+	case item.Playlist != nil:
+		return filterFields{
+			duration:  time.Duration(item.Playlist.Duration) * time.Millisecond,
+			genre:     item.Playlist.Genre,
+			user:      item.Playlist.User,
+			title:     item.Playlist.Title,
+			likes:     item.Playlist.LikesCount,
+			createdAt: item.Playlist.CreatedAt,
+			playlist:  true,
+		}, true
+	default:
+		return filterFields{}, false
+	}
+}
+
+// parseFilterDuration parses a filter duration, treating invalid values as no limit
+func parseFilterDuration(s string) time.Duration {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+// containsFold reports whether values contains s, ignoring case and surrounding space
+func containsFold(values []string, s string) bool {
+	s = strings.TrimSpace(s)
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), s) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesArtist reports whether user's username or permalink is in artists
+func matchesArtist(artists []string, user User) bool {
+	return containsFold(artists, user.Username) || containsFold(artists, user.Permalink)
+}
+
+// titleContains reports whether title contains any of keywords, ignoring case
+func titleContains(title string, keywords []string) bool {
+	title = strings.ToLower(title)
+	for _, keyword := range keywords {
+		if keyword != "" && strings.Contains(title, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// filterTestItems are the stream items the filter tests run against
+func filterTestItems() []TrackItem {
+	recent := time.Now().Add(-24 * time.Hour).Format(time.RFC3339)
+	old := time.Now().Add(-400 * 24 * time.Hour).Format(time.RFC3339)
+	poster := User{Username: "Poster", Permalink: "poster"}
+
+	return []TrackItem{
+		{Type: "track", User: User{Username: "DJ One", Permalink: "dj-one"}, Track: &Track{
+			ID: 1, Title: "Deep House Mix", Genre: "House", Duration: 60 * 60000,
+			User: User{Username: "DJ One", Permalink: "dj-one"}, PlaybackCount: 500, LikesCount: 50, CreatedAt: recent,
+		}},
+		{Type: "track", User: User{Username: "DJ Two", Permalink: "dj-two"}, Track: &Track{
+			ID: 2, Title: "Short Edit", Genre: "Techno", Duration: 5 * 60000,
+			User: User{Username: "DJ Two", Permalink: "dj-two"}, PlaybackCount: 5000, LikesCount: 400, CreatedAt: recent,
+		}},
+		{Type: "track-repost", User: poster, Track: &Track{
+			ID: 3, Title: "Techno Podcast 042", Genre: "techno", Duration: 90 * 60000,
+			User: User{Username: "DJ Three", Permalink: "dj-three"}, PlaybackCount: 20, LikesCount: 2, CreatedAt: old,
+		}},
+		{Type: "playlist", User: poster, Playlist: &Playlist{
+			ID: 4, Title: "Festival Sets", Genre: "House", Duration: 240 * 60000,
+			User: User{Username: "DJ One", Permalink: "dj-one"}, LikesCount: 30, CreatedAt: recent,
+		}},
+		{Type: "track", User: poster},
+	}
+}
+
+// filteredIDs returns the track and playlist IDs of items
+func filteredIDs(items []TrackItem) []int {
+	ids := []int{}
+	for _, item := range items {
+		if item.Track != nil {
+			ids = append(ids, item.Track.ID)
+		} else if item.Playlist != nil {
+			ids = append(ids, item.Playlist.ID)
+		}
+	}
+	return ids
+}
+
+func TestMixFilterApply(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter MixFilter
+		want   []int
+	}{
+		{"empty filter keeps tracks and playlists", MixFilter{}, []int{1, 2, 3, 4}},
+		{"default filter drops short tracks", defaultMixFilter, []int{1, 3, 4}},
+		{"max duration", MixFilter{MaxDuration: "1h"}, []int{1, 2}},
+		{"invalid durations don't filter", MixFilter{MinDuration: "long", MaxDuration: "1 hour"}, []int{1, 2, 3, 4}},
+		{"genres ignore case", MixFilter{Genres: []string{"TECHNO"}}, []int{2, 3}},
+		{"exclude genres", MixFilter{ExcludeGenres: []string{" house "}}, []int{2, 3}},
+		{"artists by username or permalink", MixFilter{Artists: []string{"dj one", "dj-two"}}, []int{1, 2, 4}},
+		{"block artists drops their reposts", MixFilter{BlockArtists: []string{"poster"}}, []int{1, 2}},
+		{"title keywords", MixFilter{TitleKeywords: []string{"mix", "podcast"}}, []int{1, 3}},
+		{"exclude title", MixFilter{ExcludeTitle: []string{"EDIT"}}, []int{1, 3, 4}},
+		{"exclude reposts", MixFilter{ExcludeReposts: true}, []int{1, 2, 4}},
+		{"min plays skips playlists", MixFilter{MinPlays: 100}, []int{1, 2, 4}},
+		{"min likes", MixFilter{MinLikes: 40}, []int{1, 2}},
+		{"max age", MixFilter{MaxAge: "720h"}, []int{1, 2, 4}},
+		{"combined", MixFilter{MinDuration: "30m", Genres: []string{"house"}, ExcludeReposts: true}, []int{1, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := filteredIDs(tt.filter.apply(filterTestItems()))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("apply() kept %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMixFilterFromQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name  string
+		query string
+		want  *MixFilter
+	}{
+		{"no parameters", "", nil},
+		{"unrelated parameters", "page=2&hide_heard=1", nil},
+		{
+			name:  "lists are split and trimmed",
+			query: "genre=house,+techno&genre=dnb&exclude_title=edit,,",
+			want:  &MixFilter{Genres: []string{"house", "techno", "dnb"}, ExcludeTitle: []string{"edit"}},
+		},
+		{
+			name:  "durations, counts and reposts",
+			query: "min_duration=30m&max_duration=2h&min_plays=100&min_likes=x&reposts=false&max_age=720h",
+			want:  &MixFilter{MinDuration: "30m", MaxDuration: "2h", MinPlays: 100, ExcludeReposts: true, MaxAge: "720h"},
+		},
+		{"reposts other than false", "reposts=true", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/api/soundcloud/stream?"+tt.query, nil)
+
+			got := mixFilterFromQuery(c)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mixFilterFromQuery() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
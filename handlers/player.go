@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MixStream is returned by the stream endpoint for the player bar to play a track
+type MixStream struct {
+	TrackID      int     `json:"trackId"`
+	URL          string  `json:"url"`
+	Protocol     string  `json:"protocol"` // "progressive" or "hls"
+	MimeType     string  `json:"mimeType"`
+	Position     float64 `json:"position"` // Stored position in seconds to resume from
+	Title        string  `json:"title"`
+	Artist       string  `json:"artist"`
+	ArtworkURL   string  `json:"artworkUrl"` // Through the image proxy
+	PermalinkURL string  `json:"permalinkUrl"`
+}
+
+// HandleGetMixStream handles the GET /api/soundcloud/tracks/:id/stream endpoint,
+// resolving a transcoding of the track to a playable stream URL. Stream URLs
+// expire, so they are resolved each time a track is played.
+func HandleGetMixStream(c *gin.Context) {
+	id := c.Param("id")
+	log.Printf("[GET] mix stream %s", id)
+
+	trackID, err := strconv.Atoi(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid track id"})
+		return
+	}
+	track, err := findTrack(trackID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "track not found"})
+		return
+	}
+
+	respondMixStream(c, track)
+}
+
+// errNoTranscoding is returned for tracks without a transcoding the player can use
+var errNoTranscoding = errors.New("track has no playable transcoding")
+
+// respondMixStream resolves the stream of track and writes it as the response
+func respondMixStream(c *gin.Context, track *Track) {
+	stream, err := resolveMixStream(track)
+	if err != nil {
+		respondMixStreamError(c, track.ID, err)
+		return
+	}
+	recordListen(track.ID, track, "play", stream.Position, 0)
+	c.JSON(http.StatusOK, stream)
+}
+
+// resolveMixStream resolves a stream URL for track, along with what the player shows
+func resolveMixStream(track *Track) (*MixStream, error) {
+	transcoding := pickTranscoding(track.Media.Transcodings)
+	if transcoding == nil {
+		return nil, errNoTranscoding
+	}
+
+	streamURL, err := resolveTranscoding(transcoding, track.TrackAuthorization)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MixStream{
+		TrackID:      track.ID,
+		URL:          streamURL,
+		Protocol:     transcoding.Format.Protocol,
+		MimeType:     transcoding.Format.MimeType,
+		Position:     getPlaybackPosition(mixPositionID(track.ID)).Position,
+		Title:        track.Title,
+		Artist:       track.User.Username,
+		ArtworkURL:   ImageURL(track.ArtworkURL, 96),
+		PermalinkURL: track.PermalinkURL,
+	}, nil
+}
+
+// respondMixStreamError writes a failed stream resolution as the response. The track
+// ID is included so the player can skip the mix when advancing through the queue.
+func respondMixStreamError(c *gin.Context, trackID int, err error) {
+	if err == errNoTranscoding {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "trackId": trackID})
+		return
+	}
+	log.Printf("Error resolving stream for track %d: %v", trackID, err)
+	c.JSON(http.StatusBadGateway, gin.H{"error": "failed to resolve stream", "trackId": trackID})
+}
+
+// HandlePostMixPosition handles the POST /api/soundcloud/tracks/:id/position endpoint.
+// The player posts the position as it plays, which also feeds the listening history.
+func HandlePostMixPosition(c *gin.Context) {
+	trackID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid track id")
+		return
+	}
+	position, err := strconv.ParseFloat(c.PostForm("position"), 64)
+	if err != nil {
+		c.String(http.StatusBadRequest, "position is required")
+		return
+	}
+	duration, _ := strconv.ParseFloat(c.PostForm("duration"), 64)
+
+	if err := storePlaybackPosition(mixPositionID(trackID), position, duration); err != nil {
+		log.Printf("Error storing playback position for track %d: %v", trackID, err)
+		c.String(http.StatusInternalServerError, "Failed to store position")
+		return
+	}
+	recordListen(trackID, nil, "progress", position, duration)
+
+	c.Status(http.StatusNoContent)
+}
+
+// mixPositionID is the key of a track in the playback positions store, shared with podcast episodes
+func mixPositionID(trackID int) string {
+	return "sc:" + strconv.Itoa(trackID)
+}
+
+// pickTranscoding prefers full length progressive MP3, which every browser plays,
+// over HLS, which only some play natively
+func pickTranscoding(transcodings []Transcoding) *Transcoding {
+	var best *Transcoding
+	bestScore := 0
+	for i := range transcodings {
+		t := &transcodings[i]
+		if t.Snipped || t.URL == "" {
+			continue
+		}
+
+		score := 1
+		if t.Format.Protocol == "progressive" {
+			score += 2
+		}
+		if strings.HasPrefix(t.Format.MimeType, "audio/mpeg") {
+			score++
+		}
+		if score > bestScore {
+			best = t
+			bestScore = score
+		}
+	}
+	return best
+}
+
+// resolveTranscoding asks SoundCloud for the stream URL of a transcoding
+func resolveTranscoding(transcoding *Transcoding, trackAuthorization string) (string, error) {
+	u, err := url.Parse(transcoding.URL)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("client_id", os.Getenv("sc_client_id"))
+	if trackAuthorization != "" {
+		query.Set("track_authorization", trackAuthorization)
+	}
+	u.RawQuery = query.Encode()
+
+	body, err := soundcloudGet(u.String())
+	if err != nil {
+		return "", err
+	}
+
+	var stream struct {
+		URL string `json:"url"`
+	}
+	if err := json.Unmarshal(body, &stream); err != nil {
+		return "", err
+	}
+	if stream.URL == "" {
+		return "", errors.New("no stream url in response")
+	}
+	return stream.URL, nil
+}
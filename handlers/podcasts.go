@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const playbackPositionsFile = "playback-positions.json"
+
+// PlaybackPosition records how far into an episode or track playback has progressed
+type PlaybackPosition struct {
+	Position  float64   `json:"position"` // Seconds from the start
+	Duration  float64   `json:"duration"` // Total length in seconds, when known
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// PodcastPlayer is the data rendered by the podcast-player template
+type PodcastPlayer struct {
+	Item     Item
+	Position float64
+}
+
+var playbackPositionsMu sync.Mutex
+
+// HandleGetPodcastPlayer handles the GET /api/podcasts/player endpoint
+func HandleGetPodcastPlayer(c *gin.Context) {
+	id := c.Query("id")
+	log.Printf("[GET] podcast player %s", id)
+
+	item, err := findNewsItem(id)
+	if err != nil || !item.IsPodcast() {
+		c.String(http.StatusNotFound, "Episode not found")
+		return
+	}
+
+	position := getPlaybackPosition(id)
+	c.HTML(http.StatusOK, "podcast-player", PodcastPlayer{Item: *item, Position: position.Position})
+}
+
+// HandlePostPodcastPosition handles the POST /api/podcasts/position endpoint
+func HandlePostPodcastPosition(c *gin.Context) {
+	id := c.PostForm("id")
+	position, err := strconv.ParseFloat(c.PostForm("position"), 64)
+	if id == "" || err != nil {
+		c.String(http.StatusBadRequest, "id and position are required")
+		return
+	}
+	duration, _ := strconv.ParseFloat(c.PostForm("duration"), 64)
+
+	if err := storePlaybackPosition(id, position, duration); err != nil {
+		log.Printf("Error storing playback position for %s: %v", id, err)
+		c.String(http.StatusInternalServerError, "Failed to store position")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// getPlaybackPositions reads all stored playback positions keyed by episode or track id
+func getPlaybackPositions() map[string]PlaybackPosition {
+	positions := make(map[string]PlaybackPosition)
+	if err := readJSONFile(playbackPositionsFile, &positions); err != nil {
+		return make(map[string]PlaybackPosition)
+	}
+	return positions
+}
+
+// getPlaybackPosition returns the stored position for id, or a zero position
+func getPlaybackPosition(id string) PlaybackPosition {
+	playbackPositionsMu.Lock()
+	defer playbackPositionsMu.Unlock()
+
+	return getPlaybackPositions()[id]
+}
+
+// storePlaybackPosition persists the playback position for id
+func storePlaybackPosition(id string, position float64, duration float64) error {
+	playbackPositionsMu.Lock()
+	defer playbackPositionsMu.Unlock()
+
+	positions := getPlaybackPositions()
+	positions[id] = PlaybackPosition{Position: position, Duration: duration, UpdatedAt: time.Now()}
+	return writeJSONFile(playbackPositionsFile, positions)
+}
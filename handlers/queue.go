@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	playQueueFile    = "play-queue.json"
+	queueAutofillMax = 10 // Mixes added per auto-fill
+)
+
+// QueueEntry is a mix waiting in the play queue. The track is kept in full so it
+// stays playable after it drops out of the SoundCloud caches.
+type QueueEntry struct {
+	Track   Track     `json:"track"`
+	AddedAt time.Time `json:"addedAt"`
+}
+
+var playQueueMu sync.Mutex
+
+// HandleGetQueue handles the GET /api/queue endpoint, rendering the queue panel
+func HandleGetQueue(c *gin.Context) {
+	playQueueMu.Lock()
+	queue := getPlayQueue()
+	playQueueMu.Unlock()
+
+	c.HTML(http.StatusOK, "play-queue", queue)
+}
+
+// HandlePostQueue handles the POST /api/queue endpoint, adding a mix to the end of the
+// queue or, with position=next, to the front. A mix already queued is moved.
+func HandlePostQueue(c *gin.Context) {
+	id := c.PostForm("id")
+	log.Printf("[POST] queue %s %s", id, c.PostForm("position"))
+
+	trackID, err := strconv.Atoi(id)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid track id")
+		return
+	}
+	track, err := findTrack(trackID)
+	if err != nil {
+		c.String(http.StatusNotFound, "Track not found")
+		return
+	}
+
+	updatePlayQueue(c, func(queue []QueueEntry) []QueueEntry {
+		queue = removeQueueEntry(queue, trackID)
+		entry := QueueEntry{Track: *track, AddedAt: time.Now()}
+		if c.PostForm("position") == "next" {
+			return append([]QueueEntry{entry}, queue...)
+		}
+		return append(queue, entry)
+	})
+}
+
+// HandlePostQueueMove handles the POST /api/queue/:id/move endpoint, moving an entry
+// one place up or down
+func HandlePostQueueMove(c *gin.Context) {
+	trackID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid track id")
+		return
+	}
+	direction := c.PostForm("direction")
+	log.Printf("[POST] queue move %d %s", trackID, direction)
+
+	updatePlayQueue(c, func(queue []QueueEntry) []QueueEntry {
+		for i := range queue {
+			if queue[i].Track.ID != trackID {
+				continue
+			}
+			j := i + 1
+			if direction == "up" {
+				j = i - 1
+			}
+			if j >= 0 && j < len(queue) {
+				queue[i], queue[j] = queue[j], queue[i]
+			}
+			break
+		}
+		return queue
+	})
+}
+
+// HandleDeleteQueueEntry handles the DELETE /api/queue/:id endpoint
+func HandleDeleteQueueEntry(c *gin.Context) {
+	trackID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid track id")
+		return
+	}
+	log.Printf("[DELETE] queue %d", trackID)
+
+	updatePlayQueue(c, func(queue []QueueEntry) []QueueEntry {
+		return removeQueueEntry(queue, trackID)
+	})
+}
+
+// HandlePostQueueClear handles the POST /api/queue/clear endpoint
+func HandlePostQueueClear(c *gin.Context) {
+	log.Printf("[POST] queue clear")
+	updatePlayQueue(c, func(queue []QueueEntry) []QueueEntry {
+		return nil
+	})
+}
+
+// HandlePostQueueAutofill handles the POST /api/queue/autofill endpoint, appending the
+// next playable mixes of the filtered stream, in stream order, that aren't queued or heard yet
+func HandlePostQueueAutofill(c *gin.Context) {
+	log.Printf("[POST] queue autofill")
+
+	// The stream options are posted along, while getFilteredMixes reads them from the query
+	if err := c.Request.ParseForm(); err == nil {
+		c.Request.URL.RawQuery = c.Request.PostForm.Encode()
+	}
+	stream := getFilteredMixes(c, "soundcloud-stream", true)
+
+	updatePlayQueue(c, func(queue []QueueEntry) []QueueEntry {
+		queued := make(map[int]bool)
+		for _, entry := range queue {
+			queued[entry.Track.ID] = true
+		}
+
+		added := 0
+		for _, item := range stream.Collection {
+			if added == queueAutofillMax {
+				break
+			}
+			if item.Track == nil || queued[item.Track.ID] || item.Track.Heard || !item.Track.IsPlayable() {
+				continue
+			}
+			queue = append(queue, QueueEntry{Track: *item.Track, AddedAt: time.Now()})
+			queued[item.Track.ID] = true
+			added++
+		}
+		return queue
+	})
+}
+
+// HandlePostQueuePlay handles the POST /api/queue/:id/play endpoint, returning the stream
+// of an entry for the player and taking it off the queue once the stream resolved. The
+// id "next" takes the first entry not listed in the comma separated skip form value,
+// which is how the player auto-advances past mixes that fail to play.
+func HandlePostQueuePlay(c *gin.Context) {
+	id := c.Param("id")
+	log.Printf("[POST] queue play %s", id)
+
+	skip := make(map[string]bool)
+	for _, trackID := range strings.Split(c.PostForm("skip"), ",") {
+		skip[trackID] = true
+	}
+
+	playQueueMu.Lock()
+	var entry *QueueEntry
+	for _, queued := range getPlayQueue() {
+		trackID := strconv.Itoa(queued.Track.ID)
+		if (id == "next" && !skip[trackID]) || trackID == id {
+			found := queued
+			entry = &found
+			break
+		}
+	}
+	playQueueMu.Unlock()
+	if entry == nil {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	// Resolved outside the lock, so a slow SoundCloud doesn't block the queue panel
+	stream, err := resolveMixStream(&entry.Track)
+	if err != nil {
+		respondMixStreamError(c, entry.Track.ID, err)
+		return
+	}
+
+	playQueueMu.Lock()
+	err = writeJSONFile(playQueueFile, removeQueueEntry(getPlayQueue(), entry.Track.ID))
+	playQueueMu.Unlock()
+	if err != nil {
+		log.Printf("Error storing play queue: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update queue"})
+		return
+	}
+
+	recordListen(entry.Track.ID, &entry.Track, "play", stream.Position, 0)
+	c.JSON(http.StatusOK, stream)
+}
+
+// updatePlayQueue applies update to the stored queue and renders the queue panel
+func updatePlayQueue(c *gin.Context, update func(queue []QueueEntry) []QueueEntry) {
+	playQueueMu.Lock()
+	defer playQueueMu.Unlock()
+
+	queue := update(getPlayQueue())
+	if err := writeJSONFile(playQueueFile, queue); err != nil {
+		log.Printf("Error storing play queue: %v", err)
+		c.String(http.StatusInternalServerError, "Failed to update queue")
+		return
+	}
+	c.HTML(http.StatusOK, "play-queue", queue)
+}
+
+// removeQueueEntry returns queue without the entry for trackID
+func removeQueueEntry(queue []QueueEntry, trackID int) []QueueEntry {
+	var kept []QueueEntry
+	for _, entry := range queue {
+		if entry.Track.ID != trackID {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// getPlayQueue reads the play queue. Callers must hold playQueueMu.
+func getPlayQueue() []QueueEntry {
+	var queue []QueueEntry
+	if err := readJSONFile(playQueueFile, &queue); err != nil {
+		return nil
+	}
+	return queue
+}
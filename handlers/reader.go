@@ -0,0 +1,551 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	articleCacheDir      = "article-cache"
+	maxArticleBytes      = 5 << 20 // Don't read more than 5MB of any page
+	wordsPerMinute       = 230
+	articlePrefetchLimit = 4 // Concurrent fetches while prefetching articles
+)
+
+// Article is the readable content extracted from a web page
+type Article struct {
+	URL         string        `json:"url"`
+	Title       string        `json:"title"`
+	Byline      string        `json:"byline"`
+	SiteName    string        `json:"siteName"`
+	Content     template.HTML `json:"content"` // Sanitized HTML of the main content block
+	Text        string        `json:"text"`    // Plain text of the main content block
+	WordCount   int           `json:"wordCount"`
+	ReadingTime int           `json:"readingTime"` // Estimated minutes to read
+	FetchedAt   time.Time     `json:"fetchedAt"`
+}
+
+var (
+	unlikelyCandidates = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|header|menu|modal|nav|newsletter|pager|popup|promo|related|remark|rss|share|shoutbox|sidebar|social|sponsor|subscribe|tags|tool|widget|\bad-|\bads\b`)
+	likelyCandidates   = regexp.MustCompile(`(?i)and|article|body|column|content|entry|main|page|post|shadow|story|text`)
+	positiveClass      = regexp.MustCompile(`(?i)article|body|content|entry|hentry|main|page|post|story|text|blog`)
+	negativeClass      = regexp.MustCompile(`(?i)comment|com-|contact|footer|footnote|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget|\bad-|\bads\b`)
+)
+
+// Elements that never contain article content
+var strippedElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Iframe: true,
+	atom.Form: true, atom.Nav: true, atom.Footer: true, atom.Aside: true,
+	atom.Svg: true, atom.Button: true, atom.Input: true, atom.Select: true,
+	atom.Textarea: true, atom.Object: true, atom.Embed: true, atom.Link: true,
+	atom.Meta: true, atom.Template: true,
+}
+
+// Elements (and their attributes) kept when rendering the extracted content
+var allowedElements = map[atom.Atom][]string{
+	atom.P: nil, atom.Br: nil, atom.H1: nil, atom.H2: nil, atom.H3: nil,
+	atom.H4: nil, atom.H5: nil, atom.H6: nil, atom.Ul: nil, atom.Ol: nil,
+	atom.Li: nil, atom.Blockquote: nil, atom.Pre: nil, atom.Code: nil,
+	atom.Em: nil, atom.Strong: nil, atom.B: nil, atom.I: nil, atom.Hr: nil,
+	atom.Figure: nil, atom.Figcaption: nil, atom.Table: nil, atom.Thead: nil,
+	atom.Tbody: nil, atom.Tr: nil, atom.Th: nil, atom.Td: nil,
+	atom.A:   {"href"},
+	atom.Img: {"src", "alt"},
+}
+
+var articleCacheMu sync.Mutex
+
+// HandleGetReader handles the GET /read endpoint. Only the links of cached news items
+// are read, so the endpoint can't be used to fetch arbitrary or internal pages.
+func HandleGetReader(c *gin.Context) {
+	pageURL := c.Query("url")
+	log.Printf("[GET] read %s", pageURL)
+
+	item, err := findNewsItemByLink(pageURL)
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", nil)
+		return
+	}
+
+	article, err := getArticle(item.Link)
+	if err != nil {
+		log.Printf("Error extracting article %s: %v", item.Link, err)
+		c.HTML(http.StatusBadGateway, "error.html", nil)
+		return
+	}
+
+	c.HTML(http.StatusOK, "reader.html", gin.H{
+		"title":   article.Title,
+		"article": article,
+	})
+}
+
+// PrefetchArticles extracts and caches the articles behind cached news items
+// that haven't been fetched yet, then records their reading times in the news cache
+func PrefetchArticles() {
+	news, err := getCachedNews()
+	if err != nil {
+		log.Printf("Error reading news cache for article prefetch: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, articlePrefetchLimit)
+	for _, item := range news.Collection {
+		if item.Link == "" || item.IsPodcast() || getCachedArticle(item.Link) != nil {
+			continue
+		}
+
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(link string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if _, err := getArticle(link); err != nil {
+				log.Printf("Error prefetching article %s: %v", link, err)
+			}
+		}(item.Link)
+	}
+	wg.Wait()
+
+	updateNewsCache(func(news *NewsResponse) {
+		annotateArticles(news.Collection)
+	})
+}
+
+// annotateArticles copies details of already extracted articles onto news items
+func annotateArticles(items []Item) {
+	for i := range items {
+		if article := getCachedArticle(items[i].Link); article != nil {
+			items[i].ReadingTime = article.ReadingTime
+		}
+	}
+}
+
+// getArticle returns the extracted article for pageURL, fetching it if it isn't cached
+func getArticle(pageURL string) (*Article, error) {
+	if article := getCachedArticle(pageURL); article != nil {
+		return article, nil
+	}
+
+	parsed, err := url.Parse(pageURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, errors.New("url must be an absolute http(s) URL")
+	}
+
+	body, err := fetchPage(pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	article, err := extractArticle(body, parsed)
+	if err != nil {
+		return nil, err
+	}
+	article.URL = pageURL
+
+	if err := storeCachedArticle(article); err != nil {
+		log.Printf("Error caching article %s: %v", pageURL, err)
+	}
+	return article, nil
+}
+
+// fetchPage downloads a web page, limited to maxArticleBytes
+func fetchPage(pageURL string) ([]byte, error) {
+	client := &http.Client{Timeout: 20 * time.Second}
+	req, err := http.NewRequest("GET", pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; jbhicks.dev reader)")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxArticleBytes))
+}
+
+// articleCachePath returns the cache file used for pageURL
+func articleCachePath(pageURL string) string {
+	sum := sha1.Sum([]byte(pageURL))
+	return filepath.Join(articleCacheDir, hex.EncodeToString(sum[:])+".json")
+}
+
+// getCachedArticle reads an extracted article from the cache, or returns nil on a miss
+func getCachedArticle(pageURL string) *Article {
+	articleCacheMu.Lock()
+	defer articleCacheMu.Unlock()
+
+	var article Article
+	if err := readJSONFile(articleCachePath(pageURL), &article); err != nil {
+		return nil
+	}
+	return &article
+}
+
+// storeCachedArticle writes an extracted article to the cache
+func storeCachedArticle(article *Article) error {
+	articleCacheMu.Lock()
+	defer articleCacheMu.Unlock()
+
+	if err := os.MkdirAll(articleCacheDir, 0755); err != nil {
+		return err
+	}
+	return writeJSONFile(articleCachePath(article.URL), article)
+}
+
+// extractArticle finds the main content block of an HTML page and strips the boilerplate around it
+func extractArticle(body []byte, pageURL *url.URL) (*Article, error) {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	article := &Article{FetchedAt: time.Now()}
+	readMetadata(doc, article)
+	if article.Title == "" {
+		article.Title = pageURL.Host
+	}
+
+	removeBoilerplate(doc)
+	content := findContentNode(doc)
+	if content == nil {
+		return nil, errors.New("no readable content found")
+	}
+
+	var out strings.Builder
+	for child := content.FirstChild; child != nil; child = child.NextSibling {
+		renderClean(&out, child, pageURL)
+	}
+
+	article.Content = template.HTML(out.String())
+	article.Text = strings.TrimSpace(collapseWhitespace(blockText(content)))
+	article.WordCount = len(strings.Fields(article.Text))
+	article.ReadingTime = readingTime(article.WordCount)
+	return article, nil
+}
+
+// readingTime estimates the minutes needed to read wordCount words
+func readingTime(wordCount int) int {
+	if wordCount == 0 {
+		return 0
+	}
+	return int(math.Ceil(float64(wordCount) / wordsPerMinute))
+}
+
+// readMetadata fills the title, byline and site name from the document head
+func readMetadata(doc *html.Node, article *Article) {
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.Title:
+				if article.Title == "" {
+					article.Title = strings.TrimSpace(textContent(n))
+				}
+			case atom.Meta:
+				key := attr(n, "property")
+				if key == "" {
+					key = attr(n, "name")
+				}
+				value := strings.TrimSpace(attr(n, "content"))
+				switch key {
+				case "og:title":
+					article.Title = value
+				case "og:site_name":
+					article.SiteName = value
+				case "author":
+					article.Byline = value
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+}
+
+// removeBoilerplate drops elements that never hold article content and
+// containers whose class or id mark them as page chrome
+func removeBoilerplate(n *html.Node) {
+	for child := n.FirstChild; child != nil; {
+		next := child.NextSibling
+		if child.Type == html.CommentNode {
+			n.RemoveChild(child)
+		} else if child.Type == html.ElementNode {
+			matchString := attr(child, "class") + " " + attr(child, "id")
+			unlikely := unlikelyCandidates.MatchString(matchString) &&
+				!likelyCandidates.MatchString(matchString) &&
+				child.DataAtom != atom.Body && child.DataAtom != atom.A
+			if strippedElements[child.DataAtom] || unlikely || attr(child, "hidden") != "" {
+				n.RemoveChild(child)
+			} else {
+				removeBoilerplate(child)
+			}
+		}
+		child = next
+	}
+}
+
+// findContentNode scores paragraphs into their ancestors and returns the best scoring container
+func findContentNode(doc *html.Node) *html.Node {
+	scores := make(map[*html.Node]float64)
+	var candidates []*html.Node
+	initialize := func(n *html.Node) {
+		if _, ok := scores[n]; ok {
+			return
+		}
+		candidates = append(candidates, n)
+		score := classWeight(n)
+		switch n.DataAtom {
+		case atom.Article:
+			score += 10
+		case atom.Div, atom.Main, atom.Section:
+			score += 5
+		case atom.Pre, atom.Td, atom.Blockquote:
+			score += 3
+		case atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+			score -= 3
+		case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+			score -= 5
+		}
+		scores[n] = score
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && (n.DataAtom == atom.P || n.DataAtom == atom.Pre || n.DataAtom == atom.Td) {
+			text := collapseWhitespace(textContent(n))
+			if len(text) >= 25 && n.Parent != nil {
+				score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+				initialize(n.Parent)
+				scores[n.Parent] += score
+				if grandparent := n.Parent.Parent; grandparent != nil && grandparent.Type == html.ElementNode {
+					initialize(grandparent)
+					scores[grandparent] += score / 2
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+
+	var best *html.Node
+	bestScore := 0.0
+	for _, n := range candidates {
+		score := scores[n] * (1 - linkDensity(n))
+		if best == nil || score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+
+	if best == nil {
+		return findElement(doc, atom.Body)
+	}
+	return best
+}
+
+// classWeight rewards or penalizes a node based on its class and id
+func classWeight(n *html.Node) float64 {
+	weight := 0.0
+	for _, value := range []string{attr(n, "class"), attr(n, "id")} {
+		if value == "" {
+			continue
+		}
+		if negativeClass.MatchString(value) {
+			weight -= 25
+		}
+		if positiveClass.MatchString(value) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// linkDensity is the fraction of a node's text that sits inside links
+func linkDensity(n *html.Node) float64 {
+	textLength := len(collapseWhitespace(textContent(n)))
+	if textLength == 0 {
+		return 0
+	}
+
+	linkLength := 0
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			linkLength += len(collapseWhitespace(textContent(n)))
+			return
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+
+	return float64(linkLength) / float64(textLength)
+}
+
+// renderClean writes n as HTML keeping only allowed elements and attributes,
+// with links and images resolved against the page URL
+func renderClean(out *strings.Builder, n *html.Node, base *url.URL) {
+	switch n.Type {
+	case html.TextNode:
+		out.WriteString(html.EscapeString(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	attrs, allowed := allowedElements[n.DataAtom]
+	if !allowed {
+		// Unwrap unknown containers, keeping their content
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			renderClean(out, child, base)
+		}
+		if n.DataAtom == atom.Div || n.DataAtom == atom.Section {
+			out.WriteString("\n")
+		}
+		return
+	}
+
+	out.WriteString("<" + n.Data)
+	for _, name := range attrs {
+		value := attr(n, name)
+		if name == "src" && (value == "" || strings.HasPrefix(value, "data:")) {
+			// Lazy loaded images keep the real source in a data attribute
+			value = attr(n, "data-src")
+		}
+		if name == "href" || name == "src" {
+			value = resolveURL(base, value)
+		}
+		if value == "" {
+			continue
+		}
+		out.WriteString(" " + name + `="` + html.EscapeString(value) + `"`)
+	}
+	if n.DataAtom == atom.A {
+		out.WriteString(` target="_blank" rel="noopener"`)
+	}
+	if n.DataAtom == atom.Img {
+		out.WriteString(` loading="lazy"`)
+	}
+	out.WriteString(">")
+
+	if n.DataAtom == atom.Br || n.DataAtom == atom.Hr || n.DataAtom == atom.Img {
+		return
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		renderClean(out, child, base)
+	}
+	out.WriteString("</" + n.Data + ">")
+}
+
+// resolveURL makes ref absolute against base, dropping anything that isn't http(s)
+func resolveURL(base *url.URL, ref string) string {
+	parsed, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ""
+	}
+	resolved := base.ResolveReference(parsed)
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return ""
+	}
+	return resolved.String()
+}
+
+// findElement returns the first element of the given type under n
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := findElement(child, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// attr returns the value of the named attribute of n
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// textContent concatenates all text below n
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var text strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		text.WriteString(textContent(child))
+	}
+	return text.String()
+}
+
+// blockText is like textContent but separates block level elements with newlines
+func blockText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var text strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		text.WriteString(blockText(child))
+	}
+	if n.Type == html.ElementNode {
+		switch n.DataAtom {
+		case atom.P, atom.Div, atom.Li, atom.Br, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Blockquote, atom.Pre, atom.Section, atom.Tr:
+			text.WriteString("\n")
+		}
+	}
+	return text.String()
+}
+
+// collapseWhitespace replaces runs of spaces and tabs with a single space, keeping line breaks
+func collapseWhitespace(s string) string {
+	lines := strings.Split(s, "\n")
+	var kept []string
+	for _, line := range lines {
+		line = strings.Join(strings.Fields(line), " ")
+		if line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"html"
+	"log"
+	"strings"
+	"time"
+)
+
+// RedditListing is the response of a subreddit .json listing
+type RedditListing struct {
+	Data struct {
+		Children []struct {
+			Data RedditPost `json:"data"`
+		} `json:"children"`
+	} `json:"data"`
+}
+
+// RedditPost is the subset of a Reddit link we map into an Item
+type RedditPost struct {
+	Name          string  `json:"name"`
+	Title         string  `json:"title"`
+	URL           string  `json:"url"`
+	Permalink     string  `json:"permalink"`
+	Author        string  `json:"author"`
+	Score         int     `json:"score"`
+	NumComments   int     `json:"num_comments"`
+	CreatedUTC    float64 `json:"created_utc"`
+	Thumbnail     string  `json:"thumbnail"`
+	LinkFlairText string  `json:"link_flair_text"`
+	SelftextHTML  string  `json:"selftext_html"`
+	Stickied      bool    `json:"stickied"`
+	Preview       struct {
+		Images []struct {
+			Source struct {
+				URL string `json:"url"`
+			} `json:"source"`
+		} `json:"images"`
+	} `json:"preview"`
+}
+
+// fetchRedditListing retrieves a subreddit listing, e.g. https://www.reddit.com/r/golang/hot.json
+func fetchRedditListing(url string, sourceName string) ([]Item, error) {
+	log.Printf("Fetching Reddit listing from %s", url)
+
+	var listing RedditListing
+	if err := fetchJSON(url, &listing); err != nil {
+		return nil, err
+	}
+
+	var items []Item
+	for _, child := range listing.Data.Children {
+		post := child.Data
+		if post.Stickied {
+			continue
+		}
+
+		comments := "https://www.reddit.com" + post.Permalink
+		publishedAt := time.Unix(int64(post.CreatedUTC), 0)
+		item := Item{
+			ID:           itemID(post.Name, comments),
+			Title:        html.UnescapeString(post.Title),
+			Link:         post.URL,
+			Description:  html.UnescapeString(post.SelftextHTML),
+			Comments:     comments,
+			Source:       sourceName,
+			PublishedAt:  publishedAt,
+			TimePassed:   formatTimePassed(publishedAt),
+			ImageURL:     redditImage(post),
+			Points:       post.Score,
+			HasPoints:    true,
+			CommentCount: post.NumComments,
+			Author:       post.Author,
+		}
+		if post.LinkFlairText != "" {
+			item.Tags = []string{post.LinkFlairText}
+		}
+		items = append(items, item)
+	}
+
+	log.Printf("Fetched %d items from %s", len(items), sourceName)
+	return items, nil
+}
+
+// redditImage picks the post thumbnail, preferring the full preview image
+func redditImage(post RedditPost) string {
+	if len(post.Preview.Images) > 0 {
+		return html.UnescapeString(post.Preview.Images[0].Source.URL)
+	}
+	// Reddit uses placeholders such as "self", "default" and "nsfw" when there's no thumbnail
+	if strings.HasPrefix(post.Thumbnail, "http") {
+		return post.Thumbnail
+	}
+	return ""
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
@@ -10,7 +12,9 @@ import (
 	"net/http"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -23,21 +27,114 @@ type RSS struct {
 }
 
 type Channel struct {
-	Title       string `xml:"title"`
-	Description string `xml:"description"`
-	Link        string `xml:"link"`
-	Items       []Item `xml:"item"`
+	Title       string     `xml:"title"`
+	Description string     `xml:"description"`
+	AtomLinks   []AtomLink `xml:"http://www.w3.org/2005/Atom link"` // Must precede Link so atom:link elements land here
+	Link        string     `xml:"link"`
+	Items       []Item     `xml:"item"`
+}
+
+// AtomLink is an atom:link element, used by feeds to advertise their WebSub hub and canonical URL
+type AtomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+// linkHref returns the href of the first atom:link with the given rel
+func (c Channel) linkHref(rel string) string {
+	return atomLinkHref(c.AtomLinks, rel)
+}
+
+// atomLinkHref returns the href of the first link with the given rel, where a
+// missing rel means "alternate"
+func atomLinkHref(links []AtomLink, rel string) string {
+	for _, link := range links {
+		if link.Rel == rel || (link.Rel == "" && rel == "alternate") {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+// AtomFeed is an Atom feed, which parseFeed converts to the RSS structure
+type AtomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	Links   []AtomLink  `xml:"link"`
+	Entries []AtomEntry `xml:"entry"`
+}
+
+type AtomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Links     []AtomLink `xml:"link"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Summary   AtomText   `xml:"summary"`
+	Content   AtomText   `xml:"content"`
+}
+
+// AtomText is an Atom text construct. Its body is kept as inner XML, because
+// type="xhtml" content is a div of markup rather than escaped text.
+type AtomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",innerxml"`
+}
+
+// String returns the text as HTML
+func (t AtomText) String() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.Body)
+	}
+	var text string
+	if err := xml.Unmarshal([]byte("<t>"+t.Body+"</t>"), &text); err != nil {
+		return t.Body
+	}
+	return strings.TrimSpace(text)
 }
 
 type Item struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
-	Source      string `xml:"-"` // Track the source feed
-	PublishedAt time.Time
-	TimePassed  string
-	ImageURL    string `xml:"-"` // Store the image URL from the feed
+	ID           string `xml:"-"` // Stable identifier derived from the guid or link
+	GUID         string `xml:"guid"`
+	Title        string `xml:"title"`
+	Link         string `xml:"link"`
+	Description  string `xml:"description"`
+	Content      string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PubDate      string `xml:"pubDate"`
+	Comments     string `xml:"comments"` // Discussion URL, e.g. the HN comments page
+	Source       string `xml:"-"`        // Track the source feed
+	PublishedAt  time.Time
+	TimePassed   string
+	ImageURL     string     `xml:"-"` // Store the image URL from the feed
+	Enclosure    *Enclosure `xml:"enclosure"`
+	Duration     string     `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	DurationText string     `xml:"-"`
+	ReadingTime  int        `xml:"-"` // Estimated minutes to read the linked article
+	Summary      []string   `xml:"-"` // Extractive summary sentences
+	Points       int        `xml:"-"`
+	HasPoints    bool       `xml:"-"` // Whether the source scores its items, e.g. HN, Reddit or Lobsters
+	CommentCount int        `xml:"-"`
+	Author       string     `xml:"-"`
+	Tags         []string   `xml:"-"` // Flair or tags from the source
+	Saved        bool       `xml:"-" json:"-"`
+	SnapshotID   string     `xml:"-" json:"-"`
+}
+
+// SaveButton returns the data for the item's save toggle
+func (i Item) SaveButton() SaveButton {
+	return SaveButton{Kind: "news", ID: i.ID, Saved: i.Saved}
+}
+
+// Enclosure is a media file attached to an RSS item, typically a podcast episode
+type Enclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// IsPodcast reports whether the item carries an audio enclosure
+func (i Item) IsPodcast() bool {
+	return i.Enclosure != nil && i.Enclosure.URL != "" && strings.HasPrefix(i.Enclosure.Type, "audio/")
 }
 
 // NewsResponse holds all news items from various sources
@@ -48,8 +145,10 @@ type NewsResponse struct {
 
 // Feed configuration
 type FeedConfig struct {
-	Name string `json:"name"`
-	URL  string `json:"url"`
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	Type      string `json:"type,omitempty"`      // "rss" (default), "reddit" or "lobsters"
+	MinPoints int    `json:"minPoints,omitempty"` // Drop scored items below this many points
 }
 
 // Default feeds to fetch
@@ -59,6 +158,9 @@ var defaultFeeds = []FeedConfig{
 	{Name: "TechCrunch", URL: "https://techcrunch.com/feed/"},
 }
 
+// newsCacheMu serializes writes to the news cache file
+var newsCacheMu sync.Mutex
+
 // HandleGetNews handles the GET /api/news endpoint
 func HandleGetNews(c *gin.Context) {
 	log.Printf("[GET] news")
@@ -76,6 +178,26 @@ func HandleGetNews(c *gin.Context) {
 		news, _ = getCachedNews()
 	}
 
+	switch c.Query("filter") {
+	case "podcasts":
+		news.Collection = filterPodcasts(news.Collection)
+	case "scored":
+		news.Collection = filterScored(news.Collection)
+	}
+	if minPoints, err := strconv.Atoi(c.Query("min_points")); err == nil {
+		news.Collection = filterMinPoints(news.Collection, minPoints)
+	}
+	if c.Query("sort") == "points" {
+		sortByPoints(news.Collection)
+	}
+
+	saved := savedItemIDs()
+	snapshots := snapshotIDsByURL()
+	for i := range news.Collection {
+		news.Collection[i].Saved = saved["news:"+news.Collection[i].ID]
+		news.Collection[i].SnapshotID = snapshots[news.Collection[i].Link]
+	}
+
 	log.Printf("Got News Items: %d", len(news.Collection))
 	c.Writer.Header().Set("Content-Type", "text/html")
 
@@ -93,21 +215,146 @@ func LoadNewsCache() {
 
 	// Fetch and process each feed
 	for _, feed := range feeds {
-		items, err := fetchRSSFeed(feed.URL, feed.Name)
+		items, err := fetchFeed(feed)
 		if err != nil {
-			log.Printf("Error fetching RSS feed %s: %v", feed.Name, err)
+			log.Printf("Error fetching feed %s: %v", feed.Name, err)
 			continue
 		}
+		enrichHackerNews(items)
+		items = filterMinPoints(items, feed.MinPoints)
 		newsResponse.Collection = append(newsResponse.Collection, items...)
 	}
 
+	// The previous cache is read under the lock so pushes that arrive meanwhile aren't lost
+	newsCacheMu.Lock()
+	previous, previousErr := getCachedNews()
+	if previousErr == nil {
+		newsResponse.Collection = keepPushedItems(newsResponse.Collection, previous.Collection)
+	}
+
 	// Sort news items by published date, newest first
 	sort.Slice(newsResponse.Collection, func(i, j int) bool {
 		return newsResponse.Collection[i].PublishedAt.After(newsResponse.Collection[j].PublishedAt)
 	})
 
+	// Carry over reading times of articles that were already extracted
+	annotateArticles(newsResponse.Collection)
+
+	// Keep summaries from the previous load until SummarizeNews runs again
+	newItems := len(newsResponse.Collection)
+	if previousErr == nil {
+		summaries := make(map[string][]string)
+		for _, item := range previous.Collection {
+			summaries[item.ID] = item.Summary
+		}
+		newItems = 0
+		for i := range newsResponse.Collection {
+			summary, known := summaries[newsResponse.Collection[i].ID]
+			newsResponse.Collection[i].Summary = summary
+			if !known {
+				newItems++
+			}
+		}
+	}
+
 	// Store the results
-	storeNewsCache(&newsResponse)
+	err := storeNewsCache(&newsResponse)
+	newsCacheMu.Unlock()
+	if err != nil {
+		log.Printf("Error storing news cache: %v", err)
+		return
+	}
+	publishNewsEvent(newItems)
+
+	// Alerts are deduplicated per item, so failed deliveries are retried on the next load
+	evaluateAlerts(newsResponse.Collection)
+}
+
+// keepPushedItems adds the previous items that the feeds no longer list but that are newer
+// than the oldest fetched item of their source. Those arrived by WebSub push, and polled
+// feeds often lag behind the hub.
+func keepPushedItems(items []Item, previous []Item) []Item {
+	listed := make(map[string]bool)
+	oldest := make(map[string]time.Time)
+	for _, item := range items {
+		listed[item.ID] = true
+		if t, ok := oldest[item.Source]; !ok || item.PublishedAt.Before(t) {
+			oldest[item.Source] = item.PublishedAt
+		}
+	}
+
+	for _, item := range previous {
+		t, ok := oldest[item.Source]
+		if ok && !listed[item.ID] && item.PublishedAt.After(t) {
+			listed[item.ID] = true
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// updateNewsCache applies update to the cached news and stores the result
+func updateNewsCache(update func(news *NewsResponse)) {
+	newsCacheMu.Lock()
+	defer newsCacheMu.Unlock()
+
+	news, err := getCachedNews()
+	if err != nil {
+		log.Printf("Error reading news cache for update: %v", err)
+		return
+	}
+
+	update(news)
+	if err := storeNewsCache(news); err != nil {
+		log.Printf("Error storing news cache: %v", err)
+	}
+}
+
+// mergeNewsItems adds items to the news cache, replacing cached items with the same ID,
+// and returns how many of them were new. Items below the MinPoints of their feed are
+// dropped, as LoadNewsCache does.
+func mergeNewsItems(items []Item) int {
+	minPoints := make(map[string]int)
+	for _, feed := range getConfiguredFeeds() {
+		minPoints[feed.Name] = feed.MinPoints
+	}
+	var kept []Item
+	for _, item := range items {
+		if !belowMinPoints(item, minPoints[item.Source]) {
+			kept = append(kept, item)
+		}
+	}
+	items = kept
+
+	added := 0
+	updateNewsCache(func(news *NewsResponse) {
+		existing := make(map[string]int)
+		for i, item := range news.Collection {
+			existing[item.ID] = i
+		}
+
+		for _, item := range items {
+			if i, ok := existing[item.ID]; ok {
+				item.ReadingTime = news.Collection[i].ReadingTime
+				item.Summary = news.Collection[i].Summary
+				news.Collection[i] = item
+				continue
+			}
+			existing[item.ID] = len(news.Collection)
+			news.Collection = append(news.Collection, item)
+			added++
+		}
+
+		sort.SliceStable(news.Collection, func(i, j int) bool {
+			return news.Collection[i].PublishedAt.After(news.Collection[j].PublishedAt)
+		})
+	})
+
+	if added > 0 {
+		publishNewsEvent(added)
+	}
+	evaluateAlerts(items)
+	return added
 }
 
 // getConfiguredFeeds returns the list of feeds to fetch
@@ -130,7 +377,72 @@ func getConfiguredFeeds() []FeedConfig {
 	return feeds
 }
 
-// fetchRSSFeed retrieves and parses an RSS feed
+// fetchFeed retrieves a feed using the source adapter for its type
+func fetchFeed(feed FeedConfig) ([]Item, error) {
+	switch feed.Type {
+	case "", "rss":
+		return fetchRSSFeed(feed.URL, feed.Name)
+	case "reddit":
+		return fetchRedditListing(feed.URL, feed.Name)
+	case "lobsters":
+		return fetchLobstersStories(feed.URL, feed.Name)
+	default:
+		return nil, fmt.Errorf("unsupported feed type: %s", feed.Type)
+	}
+}
+
+// fetchJSON retrieves url and decodes the JSON response into v
+func fetchJSON(url string, v interface{}) error {
+	client := &http.Client{Timeout: 20 * time.Second}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	// Reddit rejects requests with generic user agents
+	req.Header.Set("User-Agent", "jbhicks.dev dashboard")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// filterMinPoints drops scored items with fewer than minPoints points, leaving unscored items untouched
+func filterMinPoints(items []Item, minPoints int) []Item {
+	if minPoints <= 0 {
+		return items
+	}
+
+	var kept []Item
+	for _, item := range items {
+		if !belowMinPoints(item, minPoints) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// belowMinPoints reports whether a scored item has fewer than minPoints points
+func belowMinPoints(item Item, minPoints int) bool {
+	return minPoints > 0 && item.HasPoints && item.Points < minPoints
+}
+
+// sortByPoints orders items by points, highest first, keeping date order between equals
+func sortByPoints(items []Item) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Points > items[j].Points
+	})
+}
+
+// fetchRSSFeed retrieves and parses an RSS or Atom feed
 func fetchRSSFeed(url string, sourceName string) ([]Item, error) {
 	log.Printf("Fetching RSS feed from %s", url)
 
@@ -151,12 +463,73 @@ func fetchRSSFeed(url string, sourceName string) ([]Item, error) {
 		return nil, err
 	}
 
-	var rss RSS
-	err = xml.Unmarshal(body, &rss)
+	rss, err := parseFeed(body)
 	if err != nil {
 		return nil, err
 	}
 
+	// Subscribe for pushed updates when the feed advertises a WebSub hub
+	if hub := rss.Channel.linkHref("hub"); hub != "" {
+		topic := rss.Channel.linkHref("self")
+		if topic == "" {
+			topic = url
+		}
+		ensureWebSubSubscription(hub, topic, sourceName)
+	}
+
+	items := rssItems(rss, sourceName)
+	log.Printf("Fetched %d items from %s", len(items), sourceName)
+	return items, nil
+}
+
+// parseFeed decodes an RSS or Atom document. Atom feeds are converted to the RSS
+// structure, with their links as the channel's atom:link elements.
+func parseFeed(body []byte) (*RSS, error) {
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := xml.Unmarshal(body, &root); err != nil {
+		return nil, err
+	}
+	if root.XMLName.Local != "feed" {
+		var rss RSS
+		if err := xml.Unmarshal(body, &rss); err != nil {
+			return nil, err
+		}
+		return &rss, nil
+	}
+
+	var atom AtomFeed
+	if err := xml.Unmarshal(body, &atom); err != nil {
+		return nil, err
+	}
+	rss := &RSS{Channel: Channel{
+		Title:     atom.Title,
+		AtomLinks: atom.Links,
+		Link:      atomLinkHref(atom.Links, "alternate"),
+	}}
+	for _, entry := range atom.Entries {
+		item := Item{
+			GUID:        entry.ID,
+			Title:       entry.Title,
+			Link:        atomLinkHref(entry.Links, "alternate"),
+			Description: entry.Summary.String(),
+			Content:     entry.Content.String(),
+			PubDate:     entry.Published,
+		}
+		if item.Description == "" {
+			item.Description = item.Content
+		}
+		if item.PubDate == "" {
+			item.PubDate = entry.Updated
+		}
+		rss.Channel.Items = append(rss.Channel.Items, item)
+	}
+	return rss, nil
+}
+
+// rssItems converts the items of an RSS document into news items from sourceName
+func rssItems(rss *RSS, sourceName string) []Item {
 	var items []Item
 	for _, item := range rss.Channel.Items {
 		// Parse publication date
@@ -174,11 +547,65 @@ func fetchRSSFeed(url string, sourceName string) ([]Item, error) {
 		// Extract image URL from the description
 		item.ImageURL = extractImageFromDescription(item.Description)
 
+		item.ID = itemID(item.GUID, item.Link)
+		item.DurationText = formatPodcastDuration(item.Duration)
+
 		items = append(items, item)
 	}
 
-	log.Printf("Fetched %d items from %s", len(items), sourceName)
-	return items, nil
+	return items
+}
+
+// itemID derives a short stable identifier for an item from its guid, falling back to the link
+func itemID(guid string, link string) string {
+	key := strings.TrimSpace(guid)
+	if key == "" {
+		key = strings.TrimSpace(link)
+	}
+	sum := sha1.Sum([]byte(key))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// filterScored returns only the items their source scored, so point rankings leave
+// out plain RSS items
+func filterScored(items []Item) []Item {
+	var scored []Item
+	for _, item := range items {
+		if item.HasPoints {
+			scored = append(scored, item)
+		}
+	}
+	return scored
+}
+
+// filterPodcasts returns only the items that carry an audio enclosure
+func filterPodcasts(items []Item) []Item {
+	var podcasts []Item
+	for _, item := range items {
+		if item.IsPodcast() {
+			podcasts = append(podcasts, item)
+		}
+	}
+	return podcasts
+}
+
+// formatPodcastDuration converts an itunes:duration value (seconds, MM:SS or HH:MM:SS) to display text
+func formatPodcastDuration(duration string) string {
+	duration = strings.TrimSpace(duration)
+	if duration == "" {
+		return ""
+	}
+
+	seconds := 0
+	for _, part := range strings.Split(duration, ":") {
+		value, err := strconv.Atoi(part)
+		if err != nil {
+			return ""
+		}
+		seconds = seconds*60 + value
+	}
+
+	return setDurationText(seconds * 1000)
 }
 
 // parsePublicationDate handles various date formats commonly used in RSS feeds
@@ -255,6 +682,38 @@ func getCachedNews() (*NewsResponse, error) {
 	return &cachedResponse, nil
 }
 
+// findNewsItemByLink looks up a cached news item by the URL it links
+func findNewsItemByLink(link string) (*Item, error) {
+	news, err := getCachedNews()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range news.Collection {
+		if link != "" && news.Collection[i].Link == link {
+			return &news.Collection[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("no news item links %s", link)
+}
+
+// findNewsItem looks up a cached news item by its ID
+func findNewsItem(id string) (*Item, error) {
+	news, err := getCachedNews()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range news.Collection {
+		if news.Collection[i].ID == id {
+			return &news.Collection[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("news item not found: %s", id)
+}
+
 // extractImageFromDescription extracts the first image URL from an HTML description
 func extractImageFromDescription(description string) string {
 	// Look for img tag
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const savedItemsFile = "saved-items.json"
+
+// SavedItem is a news article or mix kept on the read-later list
+type SavedItem struct {
+	ID       string    `json:"id"`   // Kind and source ID, e.g. "news:1a2b3c" or "mix:123456"
+	Kind     string    `json:"kind"` // "news" or "mix"
+	Title    string    `json:"title"`
+	URL      string    `json:"url"`
+	Source   string    `json:"source"` // Feed name for news, artist for mixes
+	ImageURL string    `json:"imageUrl"`
+	Note     string    `json:"note"`
+	Tags     []string  `json:"tags"`
+	SavedAt  time.Time `json:"savedAt"`
+}
+
+// SaveButton is the data rendered by the save-button template
+type SaveButton struct {
+	Kind  string
+	ID    string
+	Saved bool
+}
+
+var savedItemsMu sync.Mutex
+
+// HandleGetSaved handles the GET /saved page
+func HandleGetSaved(c *gin.Context) {
+	tag := c.Query("tag")
+	log.Printf("[GET] saved %s", tag)
+
+	savedItemsMu.Lock()
+	items := getSavedItems()
+	savedItemsMu.Unlock()
+
+	if tag != "" {
+		var tagged []SavedItem
+		for _, item := range items {
+			if hasTag(item.Tags, tag) {
+				tagged = append(tagged, item)
+			}
+		}
+		items = tagged
+	}
+
+	c.HTML(http.StatusOK, "saved.html", gin.H{
+		"title": "Saved | jbhicks.dev",
+		"items": items,
+		"tag":   tag,
+	})
+}
+
+// HandlePostSavedToggle handles the POST /api/saved/toggle endpoint used by the save buttons on cards
+func HandlePostSavedToggle(c *gin.Context) {
+	kind := c.PostForm("kind")
+	sourceID := c.PostForm("id")
+	id := kind + ":" + sourceID
+	log.Printf("[POST] saved toggle %s", id)
+
+	savedItemsMu.Lock()
+	defer savedItemsMu.Unlock()
+
+	items := getSavedItems()
+	for i, item := range items {
+		if item.ID == id {
+			items = append(items[:i], items[i+1:]...)
+			if err := writeJSONFile(savedItemsFile, items); err != nil {
+				log.Printf("Error storing saved items: %v", err)
+				c.String(http.StatusInternalServerError, "Failed to update saved items")
+				return
+			}
+			c.HTML(http.StatusOK, "save-button", SaveButton{Kind: kind, ID: sourceID, Saved: false})
+			return
+		}
+	}
+
+	item, err := newSavedItem(kind, sourceID)
+	if err != nil {
+		log.Printf("Error saving %s: %v", id, err)
+		c.String(http.StatusNotFound, "Item not found")
+		return
+	}
+
+	items = append([]SavedItem{*item}, items...)
+	if err := writeJSONFile(savedItemsFile, items); err != nil {
+		log.Printf("Error storing saved items: %v", err)
+		c.String(http.StatusInternalServerError, "Failed to update saved items")
+		return
+	}
+	c.HTML(http.StatusOK, "save-button", SaveButton{Kind: kind, ID: sourceID, Saved: true})
+}
+
+// HandlePostSavedItem handles the POST /api/saved/:id endpoint, updating the note and tags of a saved item
+func HandlePostSavedItem(c *gin.Context) {
+	id := c.Param("id")
+	log.Printf("[POST] saved %s", id)
+
+	savedItemsMu.Lock()
+	defer savedItemsMu.Unlock()
+
+	items := getSavedItems()
+	for i := range items {
+		if items[i].ID != id {
+			continue
+		}
+
+		items[i].Note = strings.TrimSpace(c.PostForm("note"))
+		items[i].Tags = parseTags(c.PostForm("tags"))
+		if err := writeJSONFile(savedItemsFile, items); err != nil {
+			log.Printf("Error storing saved items: %v", err)
+			c.String(http.StatusInternalServerError, "Failed to update saved item")
+			return
+		}
+		c.HTML(http.StatusOK, "saved-card", items[i])
+		return
+	}
+
+	c.String(http.StatusNotFound, "Saved item not found")
+}
+
+// HandleDeleteSavedItem handles the DELETE /api/saved/:id endpoint
+func HandleDeleteSavedItem(c *gin.Context) {
+	id := c.Param("id")
+	log.Printf("[DELETE] saved %s", id)
+
+	savedItemsMu.Lock()
+	defer savedItemsMu.Unlock()
+
+	items := getSavedItems()
+	for i := range items {
+		if items[i].ID == id {
+			items = append(items[:i], items[i+1:]...)
+			break
+		}
+	}
+
+	if err := writeJSONFile(savedItemsFile, items); err != nil {
+		log.Printf("Error storing saved items: %v", err)
+		c.String(http.StatusInternalServerError, "Failed to delete saved item")
+		return
+	}
+
+	// Swapping in an empty body removes the card
+	c.String(http.StatusOK, "")
+}
+
+// HandleGetSavedExportJSON handles the GET /saved/export.json endpoint
+func HandleGetSavedExportJSON(c *gin.Context) {
+	savedItemsMu.Lock()
+	items := getSavedItems()
+	savedItemsMu.Unlock()
+
+	c.Header("Content-Disposition", `attachment; filename="saved-items.json"`)
+	c.JSON(http.StatusOK, items)
+}
+
+// HandleGetSavedExportBookmarks handles the GET /saved/export.html endpoint,
+// exporting the saved items in the Netscape bookmarks format browsers import
+func HandleGetSavedExportBookmarks(c *gin.Context) {
+	savedItemsMu.Lock()
+	items := getSavedItems()
+	savedItemsMu.Unlock()
+
+	c.Header("Content-Disposition", `attachment; filename="saved-items.html"`)
+	c.HTML(http.StatusOK, "bookmarks.html", items)
+}
+
+// newSavedItem builds a saved item from the cached news item or track with the given ID
+func newSavedItem(kind string, sourceID string) (*SavedItem, error) {
+	saved := &SavedItem{ID: kind + ":" + sourceID, Kind: kind, SavedAt: time.Now()}
+
+	switch kind {
+	case "news":
+		item, err := findNewsItem(sourceID)
+		if err != nil {
+			return nil, err
+		}
+		saved.Title = item.Title
+		saved.URL = item.Link
+		saved.Source = item.Source
+		saved.ImageURL = item.ImageURL
+	case "mix":
+		trackID, err := strconv.Atoi(sourceID)
+		if err != nil {
+			return nil, err
+		}
+		track, err := findTrack(trackID)
+		if err != nil {
+			return nil, err
+		}
+		saved.Title = track.Title
+		saved.URL = track.PermalinkURL
+		saved.Source = track.User.Username
+		saved.ImageURL = track.ArtworkURL
+	default:
+		return nil, fmt.Errorf("unsupported saved item kind: %s", kind)
+	}
+
+	return saved, nil
+}
+
+// getSavedItems reads the saved items, newest first. Callers must hold savedItemsMu.
+func getSavedItems() []SavedItem {
+	var items []SavedItem
+	if err := readJSONFile(savedItemsFile, &items); err != nil {
+		return nil
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].SavedAt.After(items[j].SavedAt)
+	})
+	return items
+}
+
+// savedItemIDs returns the set of saved item IDs
+func savedItemIDs() map[string]bool {
+	savedItemsMu.Lock()
+	defer savedItemsMu.Unlock()
+
+	ids := make(map[string]bool)
+	for _, item := range getSavedItems() {
+		ids[item.ID] = true
+	}
+	return ids
+}
+
+// parseTags splits a comma separated tag list, dropping blanks and duplicates
+func parseTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !hasTag(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// hasTag reports whether tags contains tag
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,397 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	snapshotsDir          = "snapshots"
+	snapshotsIndexFile    = "snapshots.json"
+	maxSnapshotAssetBytes = 2 << 20  // Largest single image or stylesheet inlined
+	maxSnapshotBytes      = 20 << 20 // Total inlined assets per snapshot
+)
+
+// Snapshot describes a self-contained copy of a page stored on disk
+type Snapshot struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Title     string    `json:"title"`
+	Size      int       `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+var (
+	snapshotsMu    sync.Mutex
+	cssURLPattern  = regexp.MustCompile(`url\(\s*['"]?([^'")\s]+)['"]?\s*\)`)
+	cssImportRegex = regexp.MustCompile(`@import\s+(?:url\()?\s*['"]?([^'")\s;]+)['"]?\s*\)?[^;]*;`)
+)
+
+// Elements dropped from snapshots since they are either active content or can't work offline
+var snapshotStrippedElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Noscript: true, atom.Iframe: true, atom.Object: true,
+	atom.Embed: true, atom.Base: true, atom.Frame: true, atom.Frameset: true,
+}
+
+// snapshotCapture tracks the inlined asset budget while capturing a page
+type snapshotCapture struct {
+	client *http.Client
+	base   *url.URL
+	budget int
+}
+
+// HandlePostSnapshot handles the POST /api/snapshots endpoint used by the snapshot buttons on news cards
+func HandlePostSnapshot(c *gin.Context) {
+	id := c.PostForm("id")
+	log.Printf("[POST] snapshot %s", id)
+
+	item, err := findNewsItem(id)
+	if err != nil {
+		c.String(http.StatusNotFound, "News item not found")
+		return
+	}
+
+	snapshot, err := getOrCaptureSnapshot(item.Link)
+	if err != nil {
+		log.Printf("Error capturing snapshot of %s: %v", item.Link, err)
+		c.String(http.StatusBadGateway, "Snapshot failed")
+		return
+	}
+
+	c.HTML(http.StatusOK, "snapshot-link", snapshot)
+}
+
+// HandleGetSnapshot handles the GET /snapshots/:id endpoint
+func HandleGetSnapshot(c *gin.Context) {
+	id := c.Param("id")
+	log.Printf("[GET] snapshot %s", id)
+
+	if _, ok := getSnapshots()[id]; !ok {
+		c.HTML(http.StatusNotFound, "error.html", nil)
+		return
+	}
+
+	// Snapshots are third party pages, so keep them from running anything or loading remote content
+	c.Header("Content-Security-Policy", "default-src 'none'; img-src data:; style-src 'unsafe-inline' data:; font-src data:")
+	c.File(snapshotPath(id))
+}
+
+// getOrCaptureSnapshot returns the existing snapshot of pageURL or captures a new one
+func getOrCaptureSnapshot(pageURL string) (*Snapshot, error) {
+	if snapshot := findSnapshot(pageURL); snapshot != nil {
+		return snapshot, nil
+	}
+
+	parsed, err := url.Parse(pageURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, errors.New("url must be an absolute http(s) URL")
+	}
+
+	body, err := fetchPage(pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	capture := &snapshotCapture{
+		client: &http.Client{Timeout: 20 * time.Second},
+		base:   parsed,
+		budget: maxSnapshotBytes,
+	}
+	page, title, err := capture.inline(body)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{
+		ID:        itemID(pageURL, "") + fmt.Sprintf("%x", time.Now().Unix()),
+		URL:       pageURL,
+		Title:     title,
+		Size:      len(page),
+		CreatedAt: time.Now(),
+	}
+
+	snapshotsMu.Lock()
+	defer snapshotsMu.Unlock()
+
+	if err := os.MkdirAll(snapshotsDir, 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(snapshotPath(snapshot.ID), page, 0644); err != nil {
+		return nil, err
+	}
+
+	snapshots := getSnapshotsLocked()
+	snapshots[snapshot.ID] = *snapshot
+	if err := writeJSONFile(snapshotsIndexFile, snapshots); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Captured %d byte snapshot of %s", snapshot.Size, pageURL)
+	return snapshot, nil
+}
+
+// inline rewrites a page so it renders without network access, returning the page and its title
+func (s *snapshotCapture) inline(body []byte) ([]byte, string, error) {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, "", err
+	}
+
+	var title string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; {
+			next := child.NextSibling
+			if child.Type == html.ElementNode && snapshotStrippedElements[child.DataAtom] {
+				n.RemoveChild(child)
+			} else {
+				if child.Type == html.ElementNode {
+					if child.DataAtom == atom.Title && title == "" {
+						title = strings.TrimSpace(textContent(child))
+					}
+					s.inlineElement(child)
+				}
+				walk(child)
+			}
+			child = next
+		}
+	}
+	walk(doc)
+
+	if title == "" {
+		title = s.base.String()
+	}
+
+	// Note where and when the snapshot was taken at the top of the page
+	if body := findElement(doc, atom.Body); body != nil {
+		banner := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div, Attr: []html.Attribute{
+			{Key: "style", Val: "font: 13px sans-serif; padding: 6px 10px; background: #374151; color: #f9fafb;"},
+		}}
+		link := &html.Node{Type: html.ElementNode, Data: "a", DataAtom: atom.A, Attr: []html.Attribute{
+			{Key: "href", Val: s.base.String()},
+			{Key: "style", Val: "color: inherit;"},
+		}}
+		link.AppendChild(&html.Node{Type: html.TextNode, Data: s.base.String()})
+		banner.AppendChild(&html.Node{Type: html.TextNode, Data: "Snapshot of "})
+		banner.AppendChild(link)
+		banner.AppendChild(&html.Node{Type: html.TextNode, Data: " taken " + time.Now().Format("Jan 2, 2006 15:04")})
+		body.InsertBefore(banner, body.FirstChild)
+	}
+
+	var out bytes.Buffer
+	if err := html.Render(&out, doc); err != nil {
+		return nil, "", err
+	}
+	return out.Bytes(), title, nil
+}
+
+// inlineElement embeds the stylesheet or image an element refers to and drops event handlers
+func (s *snapshotCapture) inlineElement(n *html.Node) {
+	var attrs []html.Attribute
+	for _, a := range n.Attr {
+		if !strings.HasPrefix(strings.ToLower(a.Key), "on") {
+			attrs = append(attrs, a)
+		}
+	}
+	n.Attr = attrs
+
+	switch n.DataAtom {
+	case atom.Link:
+		if !strings.Contains(strings.ToLower(attr(n, "rel")), "stylesheet") {
+			return
+		}
+		href := resolveURL(s.base, attr(n, "href"))
+		css, _, err := s.fetchAsset(href)
+		if err != nil {
+			log.Printf("Skipping stylesheet %s: %v", href, err)
+			return
+		}
+		// Turn the link into an inline style element
+		n.DataAtom = atom.Style
+		n.Data = "style"
+		n.Attr = nil
+		n.AppendChild(&html.Node{Type: html.TextNode, Data: s.inlineCSS(string(css), href, true)})
+	case atom.Style:
+		if n.FirstChild != nil && n.FirstChild.Type == html.TextNode {
+			n.FirstChild.Data = s.inlineCSS(n.FirstChild.Data, s.base.String(), true)
+		}
+	case atom.Img:
+		src := attr(n, "src")
+		if lazy := attr(n, "data-src"); lazy != "" && (src == "" || strings.HasPrefix(src, "data:")) {
+			src = lazy
+		}
+		setAttr(n, "src", s.dataURI(resolveURL(s.base, src)))
+		removeAttr(n, "srcset")
+		removeAttr(n, "loading")
+	case atom.Source:
+		removeAttr(n, "srcset")
+	case atom.A:
+		if href := attr(n, "href"); href != "" && !strings.HasPrefix(href, "#") {
+			setAttr(n, "href", resolveURL(s.base, href))
+		}
+	}
+}
+
+// inlineCSS embeds the images and imports a stylesheet refers to
+func (s *snapshotCapture) inlineCSS(css string, cssURL string, followImports bool) string {
+	base, err := url.Parse(cssURL)
+	if err != nil {
+		return css
+	}
+
+	if followImports {
+		css = cssImportRegex.ReplaceAllStringFunc(css, func(match string) string {
+			importURL := resolveURL(base, cssImportRegex.FindStringSubmatch(match)[1])
+			imported, _, err := s.fetchAsset(importURL)
+			if err != nil {
+				return ""
+			}
+			return s.inlineCSS(string(imported), importURL, false)
+		})
+	}
+
+	return cssURLPattern.ReplaceAllStringFunc(css, func(match string) string {
+		ref := cssURLPattern.FindStringSubmatch(match)[1]
+		if strings.HasPrefix(ref, "data:") || strings.HasPrefix(ref, "#") {
+			return match
+		}
+		return `url("` + s.dataURI(resolveURL(base, ref)) + `")`
+	})
+}
+
+// dataURI fetches an asset and encodes it as a data: URI, or returns the
+// absolute URL unchanged when it can't be fetched or exceeds the size caps
+func (s *snapshotCapture) dataURI(assetURL string) string {
+	if assetURL == "" {
+		return ""
+	}
+
+	body, contentType, err := s.fetchAsset(assetURL)
+	if err != nil {
+		return assetURL
+	}
+	return "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(body)
+}
+
+// fetchAsset downloads an asset within the per asset cap and the remaining snapshot budget
+func (s *snapshotCapture) fetchAsset(assetURL string) ([]byte, string, error) {
+	if assetURL == "" {
+		return nil, "", errors.New("missing asset url")
+	}
+	if s.budget <= 0 {
+		return nil, "", errors.New("snapshot size budget exhausted")
+	}
+
+	resp, err := s.client.Get(assetURL)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	limit := maxSnapshotAssetBytes
+	if s.budget < limit {
+		limit = s.budget
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(limit)+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(body) > limit {
+		return nil, "", errors.New("asset exceeds size cap")
+	}
+
+	s.budget -= len(body)
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	return body, contentType, nil
+}
+
+// findSnapshot returns the latest snapshot of pageURL, or nil if there is none
+func findSnapshot(pageURL string) *Snapshot {
+	var latest *Snapshot
+	for _, snapshot := range getSnapshots() {
+		if snapshot.URL == pageURL && (latest == nil || snapshot.CreatedAt.After(latest.CreatedAt)) {
+			found := snapshot
+			latest = &found
+		}
+	}
+	return latest
+}
+
+// snapshotIDsByURL maps page URLs to their latest snapshot ID
+func snapshotIDsByURL() map[string]string {
+	ids := make(map[string]string)
+	created := make(map[string]time.Time)
+	for _, snapshot := range getSnapshots() {
+		if snapshot.CreatedAt.After(created[snapshot.URL]) {
+			ids[snapshot.URL] = snapshot.ID
+			created[snapshot.URL] = snapshot.CreatedAt
+		}
+	}
+	return ids
+}
+
+// getSnapshots reads the snapshot index keyed by ID
+func getSnapshots() map[string]Snapshot {
+	snapshotsMu.Lock()
+	defer snapshotsMu.Unlock()
+
+	return getSnapshotsLocked()
+}
+
+// getSnapshotsLocked reads the snapshot index. Callers must hold snapshotsMu.
+func getSnapshotsLocked() map[string]Snapshot {
+	snapshots := make(map[string]Snapshot)
+	if err := readJSONFile(snapshotsIndexFile, &snapshots); err != nil {
+		return make(map[string]Snapshot)
+	}
+	return snapshots
+}
+
+// snapshotPath returns the file a snapshot is stored in
+func snapshotPath(id string) string {
+	return filepath.Join(snapshotsDir, filepath.Base(id)+".html")
+}
+
+// setAttr sets the named attribute of n, adding it if missing
+func setAttr(n *html.Node, name string, value string) {
+	for i := range n.Attr {
+		if n.Attr[i].Key == name {
+			n.Attr[i].Val = value
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: name, Val: value})
+}
+
+// removeAttr removes the named attribute from n
+func removeAttr(n *html.Node, name string) {
+	for i := range n.Attr {
+		if n.Attr[i].Key == name {
+			n.Attr = append(n.Attr[:i], n.Attr[i+1:]...)
+			return
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,7 +20,7 @@ import (
 
 // Handle the GET /soundcloud/favorites endpoint
 func HandleGetSoundcloudFavorites(c *gin.Context) {
-	handleSoundcloudRequest(c, "soundcloud-favorites", false)
+	handleSoundcloudRequest(c, favoritesCacheKey, false)
 }
 
 // Handle the GET /soundcloud/stream endpoint
@@ -30,6 +31,32 @@ func HandleGetSoundcloudStream(c *gin.Context) {
 // Common function to handle Soundcloud requests
 func handleSoundcloudRequest(c *gin.Context, key string, filter bool) {
 	log.Printf("[GET] %s", key)
+	mixes := getFilteredMixes(c, key, filter)
+
+	// Favorites hold the whole like history, so the tab shows it a page at a time
+	if _, err := favoritesUserForKey(key); err == nil {
+		pageMixes(mixes, c.Request.URL.Query(), soundcloudCacheURL(key))
+	}
+
+	attachWaveforms(mixes.Collection)
+
+	saved := savedItemIDs()
+	for _, item := range mixes.Collection {
+		if item.Track != nil {
+			item.Track.Saved = saved["mix:"+strconv.Itoa(item.Track.ID)]
+		}
+	}
+
+	log.Printf("Got Mixes: %s, %d", key, len(mixes.Collection))
+	c.Writer.Header().Set("Content-Type", "text/html")
+	tmpl := template.Must(template.New("mixes.html").Funcs(TemplateFuncs()).ParseFiles("templates/mixes.html", "templates/save-button.html"))
+	if err := tmpl.ExecuteTemplate(c.Writer, "mixes.html", mixes); err != nil {
+		http.Error(c.Writer, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// getFilteredMixes returns the cached mixes for key, filtered the way its tab shows them
+func getFilteredMixes(c *gin.Context, key string, filter bool) *TracksResponse {
 	// LoadCache(key, filter) // debug force load cache
 	mixes, err := getCachedMixes(key)
 	if err != nil {
@@ -44,49 +71,90 @@ func handleSoundcloudRequest(c *gin.Context, key string, filter bool) {
 		mixes, _ = getCachedMixes(key)
 	}
 
-	log.Printf("Got Mixes: %s, %d", key, len(mixes.Collection))
-	c.Writer.Header().Set("Content-Type", "text/html")
-	tmpl := template.Must(template.ParseFiles("templates/mixes.html"))
-	if err := tmpl.ExecuteTemplate(c.Writer, "mixes.html", mixes); err != nil {
-		http.Error(c.Writer, err.Error(), http.StatusInternalServerError)
+	// Favorites store their whole like history unfiltered, so their filter applies here
+	_, favoritesErr := favoritesUserForKey(key)
+	rule := getCacheRule(key, filter)
+	if favoritesErr == nil && rule.Filter != nil {
+		mixes.Collection = rule.Filter.apply(mixes.Collection)
+	}
+	if !rule.IncludePlaylists && c.Query("playlists") != "true" {
+		mixes.Collection = withoutPlaylists(mixes.Collection)
 	}
+	if queryFilter := mixFilterFromQuery(c); queryFilter != nil {
+		mixes.Collection = queryFilter.apply(mixes.Collection)
+	}
+
+	markHeard(mixes.Collection)
+	if c.Query("hide_heard") == "true" {
+		mixes.Collection = withoutHeard(mixes.Collection)
+	}
+	return mixes
 }
 
 func LoadCache(key string, filter bool) {
-	offset := 0
-	limit := 100
+	// Favorites keep the complete like history and are synced incrementally
+	if _, err := favoritesUserForKey(key); err == nil {
+		syncFavorites(key)
+		return
+	}
+
+	rule := getCacheRule(key, filter)
+	cutoff := rule.horizonCutoff()
 	var tracks TracksResponse
 	tracks.LastUpdated = time.Now()
-	trackIDs := make(map[int]bool) // Map to track unique track IDs
+	itemKeys := make(map[string]bool) // Map to track unique tracks and playlists
+	trackCount := 0
 
 	log.Printf("Fetching Soundcloud data for key: %s", key)
-	if filter {
-		// Filter until we have 100 unique tracks
-		for len(tracks.Collection) < limit {
-			fetchedTracks := FetchSoundcloudData(key, offset, limit)
-			if len(fetchedTracks.Collection) == 0 {
-				log.Printf("No more tracks to fetch for key: %s", key)
-				break
-			}
-			fetchedTracks = filterTracks(&fetchedTracks)
-			for _, track := range fetchedTracks.Collection {
-				if !trackIDs[track.Track.ID] {
-					tracks.Collection = append(tracks.Collection, track)
-					trackIDs[track.Track.ID] = true
+	// Follow the next_href cursor until the target count, horizon or page budget is reached
+	nextHref := ""
+	for page := 1; page <= rule.MaxPages; page++ {
+		fetchedTracks := FetchSoundcloudData(key, nextHref, rule.PageSize)
+		if len(fetchedTracks.Collection) == 0 {
+			log.Printf("No more tracks to fetch for key: %s", key)
+			break
+		}
+
+		reachedHorizon := false
+		if !cutoff.IsZero() {
+			var recent []TrackItem
+			for _, item := range fetchedTracks.Collection {
+				createdAt, err := time.Parse(time.RFC3339, item.CreatedAt)
+				if err == nil && createdAt.Before(cutoff) {
+					reachedHorizon = true
+					continue
 				}
+				recent = append(recent, item)
 			}
-			offset += limit
+			fetchedTracks.Collection = recent
 		}
-	} else {
-		// Fetch tracks without additional filtering
-		fetchedTracks := FetchSoundcloudData(key, offset, limit)
-		tracks.Collection = append(tracks.Collection, fetchedTracks.Collection...)
+
+		if rule.Filter != nil {
+			fetchedTracks.Collection = rule.Filter.apply(fetchedTracks.Collection)
+		}
+		// Playlists are kept but don't count toward the target, since the tab hides them by default
+		for _, track := range fetchedTracks.Collection {
+			seenKey := itemKey(track)
+			if seenKey == "" || itemKeys[seenKey] || (track.Track != nil && trackCount >= rule.TargetCount) {
+				continue
+			}
+			tracks.Collection = append(tracks.Collection, track)
+			itemKeys[seenKey] = true
+			if track.Track != nil {
+				trackCount++
+			}
+		}
+
+		if trackCount >= rule.TargetCount || reachedHorizon || fetchedTracks.NextHref == "" {
+			break
+		}
+		nextHref = fetchedTracks.NextHref
 	}
 
 	// Sort tracks by CreatedAt in reverse order
 	sort.Slice(tracks.Collection, func(i, j int) bool {
-		timeI, errI := time.Parse(time.RFC3339, tracks.Collection[i].Track.CreatedAt)
-		timeJ, errJ := time.Parse(time.RFC3339, tracks.Collection[j].Track.CreatedAt)
+		timeI, errI := time.Parse(time.RFC3339, itemCreatedAt(tracks.Collection[i]))
+		timeJ, errJ := time.Parse(time.RFC3339, itemCreatedAt(tracks.Collection[j]))
 		if errI != nil || errJ != nil {
 			return false
 		}
@@ -95,12 +163,58 @@ func LoadCache(key string, filter bool) {
 
 	// Set display properties for each track
 	for _, track := range tracks.Collection {
-		track.Track.DurationText = setDurationText(track.Track.Duration)
-		track.Track.TimePassed = setTimePassed(track.Track.CreatedAt)
+		setDisplayProperties(track)
 	}
 
 	log.Printf("Sorted tracks by CreatedAt: %v", len(tracks.Collection))
-	storeCachedResponse(&tracks, key)
+	newTracks := countNewTracks(key, tracks.Collection)
+	if err := storeCachedResponse(&tracks, key); err != nil {
+		log.Printf("Error storing cache for %s: %v", key, err)
+		return
+	}
+	publishCacheEvent(key, newTracks)
+}
+
+// countNewTracks returns how many of items aren't in the cache stored under key
+func countNewTracks(key string, items []TrackItem) int {
+	cached, err := getCachedMixes(key)
+	if err != nil {
+		return len(items)
+	}
+
+	known := make(map[string]bool)
+	for _, item := range cached.Collection {
+		known[itemKey(item)] = true
+	}
+
+	count := 0
+	for _, item := range items {
+		if !known[itemKey(item)] {
+			count++
+		}
+	}
+	return count
+}
+
+// soundcloudCacheKeys returns the keys of every SoundCloud cache
+func soundcloudCacheKeys() []string {
+	return append([]string{"soundcloud-stream"}, FavoritesCacheKeys()...)
+}
+
+// findTrack looks up a track by ID across the SoundCloud caches
+func findTrack(id int) (*Track, error) {
+	for _, key := range soundcloudCacheKeys() {
+		mixes, err := getCachedMixes(key)
+		if err != nil {
+			continue
+		}
+		for _, item := range mixes.Collection {
+			if item.Track != nil && item.Track.ID == id {
+				return item.Track, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("track not found: %d", id)
 }
 
 func setTimePassed(s string) string {
@@ -158,18 +272,6 @@ func getCachedMixes(key string) (*TracksResponse, error) {
 	return &cachedResponse, nil
 }
 
-func filterTracks(tracks *TracksResponse) TracksResponse {
-	var filteredTracks TracksResponse
-	for _, track := range tracks.Collection {
-		if track.Track != nil &&
-			track.Track.Duration > 1750000 &&
-			!strings.Contains(track.Type, "playlist") { // check duration greater than ~30m
-			filteredTracks.Collection = append(filteredTracks.Collection, track)
-		}
-	}
-	return filteredTracks
-}
-
 func setDurationText(duration int) string {
 	seconds := duration / 1000
 	minutes := seconds / 60
@@ -183,15 +285,24 @@ func setDurationText(duration int) string {
 	}
 }
 
-func FetchSoundcloudData(endpoint string, offset int, limit int) TracksResponse {
-	log.Printf("Fetching data from Soundcloud for endpoint: %s, offset: %d, limit: %d", endpoint, offset, limit)
-	authorization := os.Getenv("sc_auth_token")
+// FetchSoundcloudData fetches one page for endpoint, the first page when nextHref is
+// empty and otherwise the page at the next_href cursor of the previous one
+func FetchSoundcloudData(endpoint string, nextHref string, limit int) TracksResponse {
+	tracksResponse, err := fetchSoundcloudPage(endpoint, nextHref, limit)
+	if err != nil {
+		log.Printf("Error fetching %s: %v", endpoint, err)
+		return TracksResponse{}
+	}
+	return *tracksResponse
+}
+
+// fetchSoundcloudPage is FetchSoundcloudData, returning errors so callers can tell
+// a failed request from the end of the collection
+func fetchSoundcloudPage(endpoint string, nextHref string, limit int) (*TracksResponse, error) {
+	log.Printf("Fetching data from Soundcloud for endpoint: %s, limit: %d, next: %s", endpoint, limit, nextHref)
 	sc_a_id := os.Getenv("sc_a_id")
 	sc_client_id := os.Getenv("sc_client_id")
 
-	if authorization == "" {
-		fmt.Println("Warning: sc_auth_token is blank")
-	}
 	if sc_a_id == "" {
 		fmt.Println("Warning: sc_a_id is blank")
 	}
@@ -200,13 +311,39 @@ func FetchSoundcloudData(endpoint string, offset int, limit int) TracksResponse
 	}
 
 	var url string
-	if endpoint == "soundcloud-stream" {
-		url = fmt.Sprintf("https://api-v2.soundcloud.com/stream?offset=%d&sc_a_id=%s&limit=%d&promoted_playlist=true&client_id=%s&app_version=1660231961&app_locale=en", offset, sc_a_id, limit, sc_client_id)
-	} else if endpoint == "soundcloud-favorites" {
-		url = fmt.Sprintf("https://api-v2.soundcloud.com/users/141564746/track_likes?offset=%d&limit=%d&client_id=%s&app_version=1731681989&app_locale=en", offset, limit, sc_client_id)
+	if nextHref != "" {
+		url = withClientID(nextHref)
+	} else if endpoint == "soundcloud-stream" {
+		url = fmt.Sprintf("https://api-v2.soundcloud.com/stream?sc_a_id=%s&limit=%d&promoted_playlist=true&client_id=%s&app_version=1660231961&app_locale=en", sc_a_id, limit, sc_client_id)
+	} else if user, err := favoritesUserForKey(endpoint); err == nil {
+		userID, err := resolveSoundcloudUserID(user.User)
+		if err != nil {
+			return nil, fmt.Errorf("resolving SoundCloud user %s: %v", user.User, err)
+		}
+		url = fmt.Sprintf("https://api-v2.soundcloud.com/users/%s/track_likes?limit=%d&client_id=%s&app_version=1731681989&app_locale=en", userID, limit, sc_client_id)
 	} else {
-		log.Printf("Unsupported endpoint: %s", endpoint)
-		return TracksResponse{}
+		return nil, fmt.Errorf("unsupported endpoint: %s", endpoint)
+	}
+
+	body, err := soundcloudGet(url)
+	if err != nil {
+		return nil, err
+	}
+
+	var tracksResponse TracksResponse
+	if err := json.Unmarshal(body, &tracksResponse); err != nil {
+		return nil, err
+	}
+	log.Printf("Fetched %d tracks from Soundcloud for endpoint: %s", len(tracksResponse.Collection), endpoint)
+	return &tracksResponse, nil
+}
+
+// soundcloudGet requests url from the SoundCloud API with the web client's headers
+// and returns the decompressed response body
+func soundcloudGet(url string) ([]byte, error) {
+	authorization := os.Getenv("sc_auth_token")
+	if authorization == "" {
+		fmt.Println("Warning: sc_auth_token is blank")
 	}
 
 	headers := map[string]string{
@@ -230,8 +367,7 @@ func FetchSoundcloudData(endpoint string, offset int, limit int) TracksResponse
 	client := &http.Client{}
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		log.Printf("Error creating request: %v", err)
-		return TracksResponse{}
+		return nil, err
 	}
 
 	for key, value := range headers {
@@ -240,42 +376,26 @@ func FetchSoundcloudData(endpoint string, offset int, limit int) TracksResponse
 
 	resp, err := client.Do(req)
 	if err != nil {
-		log.Printf("Error making request: %v", err)
-		return TracksResponse{}
+		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
 	// Check the content encoding and decompress if necessary
-	var body []byte
 	switch resp.Header.Get("Content-Encoding") {
 	case "gzip":
 		reader, err := gzip.NewReader(resp.Body)
 		if err != nil {
-			log.Printf("Error creating gzip reader: %v", err)
-			return TracksResponse{}
-		}
-		body, err = io.ReadAll(reader)
-		if err != nil {
-			log.Printf("Error reading decompressed response body: %v", err)
-			return TracksResponse{}
+			return nil, err
 		}
+		return io.ReadAll(reader)
 	default:
 		// If not GZIP, just read the entire body
-		bodyBytes, err := io.ReadAll(resp.Body)
-		if err != nil {
-			log.Printf("Error reading response body: %v", err)
-			return TracksResponse{}
-		}
-		body = bodyBytes
+		return io.ReadAll(resp.Body)
 	}
-
-	var tracksResponse TracksResponse
-	if err := json.Unmarshal(body, &tracksResponse); err != nil {
-		log.Printf("Error unmarshalling response: %v", err)
-		return TracksResponse{}
-	}
-	log.Printf("Fetched %d tracks from Soundcloud for endpoint: %s", len(tracksResponse.Collection), endpoint)
-	return tracksResponse
 }
 
 func PrettyPrint(data ...interface{}) {
@@ -294,10 +414,13 @@ type SoundCloudResponse struct {
 }
 
 type TracksResponse struct {
-	Collection  []TrackItem `json:"collection"`
-	NextHref    string      `json:"next_href"`
-	QueryUrn    *string     `json:"query_urn"`
-	LastUpdated time.Time
+	Collection       []TrackItem `json:"collection"`
+	NextHref         string      `json:"next_href"`
+	QueryUrn         *string     `json:"query_urn"`
+	LastUpdated      time.Time
+	BackfillNext     string `json:"backfill_next,omitempty"`     // Cursor the favorites backfill resumes from
+	BackfillComplete bool   `json:"backfill_complete,omitempty"` // Whether the whole like history has been fetched
+	NextPage         string `json:"-"`                           // Load more URL when rendering one page of the collection
 }
 
 type TrackItem struct {
@@ -347,46 +470,106 @@ type Reposted struct {
 }
 
 type Playlist struct {
-	// Add playlist fields as needed
+	ArtworkURL   string   `json:"artwork_url"`
+	CreatedAt    string   `json:"created_at"`
+	Duration     int      `json:"duration"`
+	DurationText string   `json:"duration_text"`
+	Genre        string   `json:"genre"`
+	ID           int      `json:"id"`
+	IsAlbum      bool     `json:"is_album"`
+	Kind         string   `json:"kind"`
+	LikesCount   int      `json:"likes_count"`
+	PermalinkURL string   `json:"permalink_url"`
+	SetType      string   `json:"set_type"` // "album", "ep", "single", "compilation" or empty for playlists
+	Title        string   `json:"title"`
+	TimePassed   string   `json:"time_passed"`
+	TrackCount   int      `json:"track_count"`
+	User         User     `json:"user"`
+	Tracks       []*Track `json:"tracks"` // Only the first few tracks are complete in stream items
+}
+
+// TypeLabel returns the kind of set for the playlist card badge
+func (p Playlist) TypeLabel() string {
+	switch {
+	case p.SetType == "ep":
+		return "EP"
+	case p.SetType != "":
+		return strings.ToUpper(p.SetType[:1]) + p.SetType[1:]
+	case p.IsAlbum:
+		return "Album"
+	default:
+		return "Playlist"
+	}
+}
+
+// Artwork returns the playlist artwork, falling back to the artwork of its first track
+func (p Playlist) Artwork() string {
+	if p.ArtworkURL == "" && len(p.Tracks) > 0 && p.Tracks[0] != nil {
+		return p.Tracks[0].ArtworkURL
+	}
+	return p.ArtworkURL
 }
 
 type Track struct {
-	ArtworkURL       string  `json:"artwork_url"`
-	Caption          *string `json:"caption"`
-	Commentable      bool    `json:"commentable"`
-	CommentCount     int     `json:"comment_count"`
-	CreatedAt        string  `json:"created_at"`
-	Description      string  `json:"description"`
-	Downloadable     bool    `json:"downloadable"`
-	DownloadCount    int     `json:"download_count"`
-	Duration         int     `json:"duration"`
-	DurationText     string  `json:"duration_text"`
-	FullDuration     int     `json:"full_duration"`
-	EmbeddableBy     string  `json:"embeddable_by"`
-	Genre            string  `json:"genre"`
-	HasDownloadsLeft bool    `json:"has_downloads_left"`
-	ID               int     `json:"id"`
-	Kind             string  `json:"kind"`
-	LabelName        *string `json:"label_name"`
-	LastModified     string  `json:"last_modified"`
-	License          string  `json:"license"`
-	Title            string  `json:"title"`
-	TimePassed       string  `json:"time_passed"`
-	PermalinkURL     string  `json:"permalink_url"`
-	User             User    `json:"user,omitempty"`
-	Media            Media   `json:"media"`
+	ArtworkURL         string        `json:"artwork_url"`
+	Caption            *string       `json:"caption"`
+	Commentable        bool          `json:"commentable"`
+	CommentCount       int           `json:"comment_count"`
+	CreatedAt          string        `json:"created_at"`
+	Description        string        `json:"description"`
+	Downloadable       bool          `json:"downloadable"`
+	DownloadCount      int           `json:"download_count"`
+	Duration           int           `json:"duration"`
+	DurationText       string        `json:"duration_text"`
+	FullDuration       int           `json:"full_duration"`
+	EmbeddableBy       string        `json:"embeddable_by"`
+	Genre              string        `json:"genre"`
+	PlaybackCount      int           `json:"playback_count"`
+	LikesCount         int           `json:"likes_count"`
+	HasDownloadsLeft   bool          `json:"has_downloads_left"`
+	ID                 int           `json:"id"`
+	Kind               string        `json:"kind"`
+	LabelName          *string       `json:"label_name"`
+	LastModified       string        `json:"last_modified"`
+	License            string        `json:"license"`
+	Title              string        `json:"title"`
+	TimePassed         string        `json:"time_passed"`
+	PermalinkURL       string        `json:"permalink_url"`
+	User               User          `json:"user,omitempty"`
+	Media              Media         `json:"media"`
+	TrackAuthorization string        `json:"track_authorization"`
+	Chapters           []Chapter     `json:"chapters,omitempty"` // Parsed from the tracklist in the description
+	WaveformURL        string        `json:"waveform_url"`
+	Waveform           []WaveformBar `json:"-"`
+	Saved              bool          `json:"-"`
+	Opened             bool          `json:"-"` // Played or opened on SoundCloud before
+	Heard              bool          `json:"-"`
+	HeardPercent       int           `json:"-"`
+}
+
+// IsPlayable reports whether the track has a full length transcoding the player can use
+func (t Track) IsPlayable() bool {
+	return pickTranscoding(t.Media.Transcodings) != nil
+}
+
+// SaveButton returns the data for the track's save toggle
+func (t Track) SaveButton() SaveButton {
+	return SaveButton{Kind: "mix", ID: strconv.Itoa(t.ID), Saved: t.Saved}
 }
 
 type Media struct {
-	Transcodings []struct {
-		URL      string `json:"url"`
-		Preset   string `json:"preset"`
-		Duration int    `json:"duration"`
-		Snipped  bool   `json:"snipped"`
-		Format   struct {
-			Protocol string `json:"protocol"`
-			MimeType string `json:"mime_type"`
-		} `json:"format"`
-		Quality string `json:"quality"`
-	} `json:"transcodings"`
+	Transcodings []Transcoding `json:"transcodings"`
+}
+
+// Transcoding is one of the encodings SoundCloud offers for a track
+type Transcoding struct {
+	URL      string `json:"url"`
+	Preset   string `json:"preset"`
+	Duration int    `json:"duration"`
+	Snipped  bool   `json:"snipped"`
+	Format   struct {
+		Protocol string `json:"protocol"`
+		MimeType string `json:"mime_type"`
+	} `json:"format"`
+	Quality string `json:"quality"`
 }
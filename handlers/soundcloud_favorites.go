@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	favoritesPageSize     = 200 // Likes fetched per request
+	favoritesPagesPerLoad = 50  // Request budget per sync, so a long backfill spreads over several loads
+	favoritesTabPageSize  = 50  // Cards per page on the favorites tab
+)
+
+// syncFavorites updates the favorites cache stored under key with the complete like
+// history. New likes are fetched newest first until a known track is reached, and
+// until the whole history is stored each sync continues the backfill where the
+// previous one stopped.
+func syncFavorites(key string) {
+	cached, err := getCachedMixes(key)
+	if err != nil {
+		cached = &TracksResponse{}
+	}
+
+	known := make(map[int]bool)
+	for _, item := range cached.Collection {
+		if item.Track != nil {
+			known[item.Track.ID] = true
+		}
+	}
+
+	log.Printf("Syncing Soundcloud favorites for key: %s", key)
+	pages := 0
+
+	var newLikes []TrackItem
+	if len(cached.Collection) > 0 {
+		nextHref := ""
+		for pages < favoritesPagesPerLoad {
+			page, err := fetchSoundcloudPage(key, nextHref, favoritesPageSize)
+			pages++
+			if err != nil {
+				log.Printf("Error fetching new likes for %s, keeping the cache: %v", key, err)
+				return
+			}
+
+			reachedKnown := false
+			for _, item := range page.Collection {
+				if item.Track == nil {
+					continue
+				}
+				if known[item.Track.ID] {
+					reachedKnown = true
+					break
+				}
+				known[item.Track.ID] = true
+				newLikes = append(newLikes, item)
+			}
+
+			if reachedKnown || page.NextHref == "" {
+				break
+			}
+			nextHref = page.NextHref
+		}
+	}
+
+	var backfilled []TrackItem
+	if !cached.BackfillComplete {
+		nextHref := cached.BackfillNext
+		for pages < favoritesPagesPerLoad {
+			page, err := fetchSoundcloudPage(key, nextHref, favoritesPageSize)
+			pages++
+			if err != nil {
+				log.Printf("Error backfilling likes for %s, resuming on the next load: %v", key, err)
+				break
+			}
+
+			for _, item := range page.Collection {
+				if item.Track != nil && !known[item.Track.ID] {
+					known[item.Track.ID] = true
+					backfilled = append(backfilled, item)
+				}
+			}
+
+			if page.NextHref == "" {
+				cached.BackfillComplete = true
+				cached.BackfillNext = ""
+				break
+			}
+			nextHref = page.NextHref
+			cached.BackfillNext = nextHref
+		}
+	}
+
+	// Likes come newest first, so new likes go on top and backfilled ones at the bottom
+	cached.Collection = append(append(newLikes, cached.Collection...), backfilled...)
+	cached.LastUpdated = time.Now()
+	for _, item := range cached.Collection {
+		setDisplayProperties(item)
+	}
+
+	log.Printf("Synced favorites for %s: %d new, %d backfilled, %d total, backfill complete: %v",
+		key, len(newLikes), len(backfilled), len(cached.Collection), cached.BackfillComplete)
+	if err := storeCachedResponse(cached, key); err != nil {
+		log.Printf("Error storing cache for %s: %v", key, err)
+		return
+	}
+	publishCacheEvent(key, len(newLikes))
+}
+
+// pageMixes trims mixes to the page of the favorites tab requested in query and sets
+// the URL of the next page when there is one. The next page keeps the rest of query,
+// so filters and hide_heard carry over to it.
+func pageMixes(mixes *TracksResponse, query url.Values, path string) {
+	n, err := strconv.Atoi(query.Get("page"))
+	if err != nil || n < 1 {
+		n = 1
+	}
+
+	start := (n - 1) * favoritesTabPageSize
+	if start > len(mixes.Collection) {
+		start = len(mixes.Collection)
+	}
+	end := start + favoritesTabPageSize
+	if end < len(mixes.Collection) {
+		next := url.Values{}
+		for k, v := range query {
+			next[k] = v
+		}
+		next.Set("page", strconv.Itoa(n+1))
+		mixes.NextPage = path + "?" + next.Encode()
+	} else {
+		end = len(mixes.Collection)
+	}
+	mixes.Collection = mixes.Collection[start:end]
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"log"
+	"net/url"
+	"os"
+	"time"
+)
+
+const (
+	defaultPageSize    = 100
+	defaultTargetCount = 100
+	defaultMaxPages    = 10
+)
+
+// CacheRule configures how LoadCache fills the cache stored under a key.
+// Paging stops at whichever of the target count, horizon and page budget is reached first.
+type CacheRule struct {
+	PageSize    int        `json:"pageSize"`
+	TargetCount int        `json:"targetCount"` // Tracks to keep after filtering
+	Horizon     string     `json:"horizon"`     // Oldest activity to include, as a duration like "720h"
+	MaxPages    int        `json:"maxPages"`
+	Filter      *MixFilter `json:"filter"` // Applied while loading, or when rendering favorites so their history stays complete
+	// Playlists are always cached, this shows them on the tab without the playlists=true toggle
+	IncludePlaylists bool `json:"includePlaylists"`
+}
+
+// getCacheRule returns the rule for key from the SoundCloud config, with defaults
+// filled in. Filtered caches default to the ~30m filter and page until enough tracks
+// pass it, unfiltered ones default to a single page.
+func getCacheRule(key string, filter bool) CacheRule {
+	rule := getSoundcloudConfig().Caches[key]
+	if rule.PageSize <= 0 {
+		rule.PageSize = defaultPageSize
+	}
+	if rule.TargetCount <= 0 {
+		rule.TargetCount = defaultTargetCount
+	}
+	if rule.Filter == nil && filter {
+		defaultFilter := defaultMixFilter
+		rule.Filter = &defaultFilter
+	}
+	if rule.MaxPages <= 0 {
+		rule.MaxPages = defaultMaxPages
+		if !filter {
+			rule.MaxPages = 1
+		}
+	}
+	return rule
+}
+
+// horizonCutoff returns the oldest activity time the rule includes, or the zero time for no limit
+func (r CacheRule) horizonCutoff() time.Time {
+	if r.Horizon == "" {
+		return time.Time{}
+	}
+	horizon, err := time.ParseDuration(r.Horizon)
+	if err != nil {
+		log.Printf("Ignoring invalid cache horizon %q: %v", r.Horizon, err)
+		return time.Time{}
+	}
+	return time.Now().Add(-horizon)
+}
+
+// withClientID adds the client_id parameter to a next_href cursor URL, which SoundCloud leaves out
+func withClientID(nextHref string) string {
+	u, err := url.Parse(nextHref)
+	if err != nil {
+		return nextHref
+	}
+	query := u.Query()
+	if query.Get("client_id") == "" {
+		query.Set("client_id", os.Getenv("sc_client_id"))
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
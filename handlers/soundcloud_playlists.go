@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const tracksPerLookup = 50 // Most track IDs the tracks endpoint accepts per request
+
+// HandleGetSoundcloudPlaylist handles the GET /api/soundcloud/playlists/:id endpoint,
+// rendering the tracks of a playlist for the drill-down on playlist cards
+func HandleGetSoundcloudPlaylist(c *gin.Context) {
+	id := c.Param("id")
+	log.Printf("[GET] soundcloud playlist %s", id)
+
+	if _, err := strconv.Atoi(id); err != nil {
+		c.String(http.StatusBadRequest, "Invalid playlist ID")
+		return
+	}
+
+	playlist, err := fetchPlaylist(id)
+	if err != nil {
+		log.Printf("Error fetching playlist %s: %v", id, err)
+		c.String(http.StatusBadGateway, "Failed to load playlist")
+		return
+	}
+
+	c.HTML(http.StatusOK, "playlist-tracks", playlist)
+}
+
+// fetchPlaylist fetches a playlist with the full details of every track. The playlist
+// endpoint only includes the first few tracks in full, so the rest are looked up by ID.
+func fetchPlaylist(id string) (*Playlist, error) {
+	clientID := os.Getenv("sc_client_id")
+	body, err := soundcloudGet(fmt.Sprintf("https://api-v2.soundcloud.com/playlists/%s?representation=full&client_id=%s", id, clientID))
+	if err != nil {
+		return nil, err
+	}
+
+	var playlist Playlist
+	if err := json.Unmarshal(body, &playlist); err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, track := range playlist.Tracks {
+		if track != nil && track.Title == "" {
+			missing = append(missing, strconv.Itoa(track.ID))
+		}
+	}
+
+	full := make(map[int]*Track)
+	for start := 0; start < len(missing); start += tracksPerLookup {
+		end := start + tracksPerLookup
+		if end > len(missing) {
+			end = len(missing)
+		}
+		body, err := soundcloudGet(fmt.Sprintf("https://api-v2.soundcloud.com/tracks?ids=%s&client_id=%s", strings.Join(missing[start:end], ","), clientID))
+		if err != nil {
+			return nil, err
+		}
+		var tracks []*Track
+		if err := json.Unmarshal(body, &tracks); err != nil {
+			return nil, err
+		}
+		for _, track := range tracks {
+			full[track.ID] = track
+		}
+	}
+
+	// Keep the playlist order, dropping tracks that are no longer available
+	var tracks []*Track
+	for _, track := range playlist.Tracks {
+		if track == nil {
+			continue
+		}
+		if track.Title == "" {
+			if track = full[track.ID]; track == nil {
+				continue
+			}
+		}
+		track.DurationText = setDurationText(track.Duration)
+		tracks = append(tracks, track)
+	}
+	playlist.Tracks = tracks
+	playlist.DurationText = setDurationText(playlist.Duration)
+	return &playlist, nil
+}
+
+// itemKey identifies the track or playlist of a stream item, or returns "" for items with neither
+func itemKey(item TrackItem) string {
+	if item.Track != nil {
+		return "track:" + strconv.Itoa(item.Track.ID)
+	}
+	if item.Playlist != nil {
+		return "playlist:" + strconv.Itoa(item.Playlist.ID)
+	}
+	return ""
+}
+
+// itemCreatedAt returns when the track or playlist of a stream item was uploaded
+func itemCreatedAt(item TrackItem) string {
+	if item.Track != nil {
+		return item.Track.CreatedAt
+	}
+	if item.Playlist != nil {
+		return item.Playlist.CreatedAt
+	}
+	return ""
+}
+
+// setDisplayProperties fills in the precomputed display fields of a stream item
+func setDisplayProperties(item TrackItem) {
+	if item.Track != nil {
+		item.Track.DurationText = setDurationText(item.Track.Duration)
+		item.Track.TimePassed = setTimePassed(item.Track.CreatedAt)
+		item.Track.Chapters = parseTracklist(item.Track.Description, item.Track.PermalinkURL)
+	}
+	if item.Playlist != nil {
+		item.Playlist.DurationText = setDurationText(item.Playlist.Duration)
+		item.Playlist.TimePassed = setTimePassed(item.Playlist.CreatedAt)
+	}
+}
+
+// withoutPlaylists drops the playlist items from items
+func withoutPlaylists(items []TrackItem) []TrackItem {
+	var tracks []TrackItem
+	for _, item := range items {
+		if item.Playlist == nil {
+			tracks = append(tracks, item)
+		}
+	}
+	return tracks
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	soundcloudConfigFile   = "soundcloud-config.json"
+	defaultFavoritesUserID = "141564746"
+	favoritesCacheKey      = "soundcloud-favorites"
+)
+
+// SoundcloudConfig is read from soundcloud-config.json
+type SoundcloudConfig struct {
+	Favorites []FavoritesUser      `json:"favorites"`
+	Caches    map[string]CacheRule `json:"caches"` // Per cache key settings
+}
+
+// FavoritesUser is a SoundCloud user whose likes get their own favorites tab
+type FavoritesUser struct {
+	Name string `json:"name"` // Tab label, also used in the cache key and URL
+	User string `json:"user"` // Numeric user ID, profile permalink or profile URL
+}
+
+// FavoritesTab is the data for one favorites tab on the dashboard
+type FavoritesTab struct {
+	Label string
+	Key   string // Cache key
+	Panel string // Panel element ID, also the prefix of its SSE event and content IDs
+	URL   string
+}
+
+var (
+	favoritesNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+	resolvedUsersMu sync.Mutex
+	resolvedUsers   = make(map[string]string) // Permalink to user ID
+)
+
+// HandleGetSoundcloudUserFavorites handles the GET /api/soundcloud/favorites/:name endpoint
+func HandleGetSoundcloudUserFavorites(c *gin.Context) {
+	name := c.Param("name")
+	for i, user := range getFavoritesUsers() {
+		if user.Name == name {
+			handleSoundcloudRequest(c, favoritesKey(i, user), false)
+			return
+		}
+	}
+	c.String(http.StatusNotFound, "Unknown favorites user")
+}
+
+// FavoritesCacheKeys returns the cache key of every configured favorites user
+func FavoritesCacheKeys() []string {
+	var keys []string
+	for i, user := range getFavoritesUsers() {
+		keys = append(keys, favoritesKey(i, user))
+	}
+	return keys
+}
+
+// FavoritesTabs returns the favorites tabs shown on the dashboard
+func FavoritesTabs() []FavoritesTab {
+	users := getFavoritesUsers()
+	var tabs []FavoritesTab
+	for i, user := range users {
+		key := favoritesKey(i, user)
+		label := "Favorites"
+		if len(users) > 1 {
+			label = "Favorites: " + user.Name
+		}
+		tabs = append(tabs, FavoritesTab{
+			Label: label,
+			Key:   key,
+			Panel: strings.TrimPrefix(key, "soundcloud-"),
+			URL:   soundcloudCacheURL(key),
+		})
+	}
+	return tabs
+}
+
+// getFavoritesUsers returns the configured favorites users. The first user keeps
+// the original soundcloud-favorites cache, and without a config it is the default user.
+func getFavoritesUsers() []FavoritesUser {
+	config := getSoundcloudConfig()
+
+	var users []FavoritesUser
+	for _, user := range config.Favorites {
+		if !favoritesNamePattern.MatchString(user.Name) || user.User == "" {
+			log.Printf("Skipping favorites user %q: names must be lowercase letters, digits and dashes", user.Name)
+			continue
+		}
+		users = append(users, user)
+	}
+
+	if len(users) == 0 {
+		return []FavoritesUser{{Name: "favorites", User: defaultFavoritesUserID}}
+	}
+	return users
+}
+
+// getSoundcloudConfig reads soundcloud-config.json, returning an empty config when it is missing
+func getSoundcloudConfig() *SoundcloudConfig {
+	var config SoundcloudConfig
+	if err := readJSONFile(soundcloudConfigFile, &config); err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Error reading SoundCloud config, using defaults: %v", err)
+		}
+		return &SoundcloudConfig{}
+	}
+	return &config
+}
+
+// favoritesKey returns the cache key for the favorites user at index i
+func favoritesKey(i int, user FavoritesUser) string {
+	if i == 0 {
+		return favoritesCacheKey
+	}
+	return favoritesCacheKey + "-" + user.Name
+}
+
+// favoritesUserForKey returns the favorites user stored under key
+func favoritesUserForKey(key string) (*FavoritesUser, error) {
+	for i, user := range getFavoritesUsers() {
+		if favoritesKey(i, user) == key {
+			return &user, nil
+		}
+	}
+	return nil, fmt.Errorf("no favorites user for key: %s", key)
+}
+
+// soundcloudCacheURL returns the dashboard endpoint that renders the cache stored under key
+func soundcloudCacheURL(key string) string {
+	if strings.HasPrefix(key, favoritesCacheKey+"-") {
+		return "/api/soundcloud/favorites/" + strings.TrimPrefix(key, favoritesCacheKey+"-")
+	}
+	return "/api/soundcloud/" + strings.TrimPrefix(key, "soundcloud-")
+}
+
+// resolveSoundcloudUserID returns the numeric ID of user, looking up permalinks
+// and profile URLs through the resolve API
+func resolveSoundcloudUserID(user string) (string, error) {
+	user = strings.TrimSpace(user)
+	if _, err := strconv.Atoi(user); err == nil {
+		return user, nil
+	}
+
+	profileURL := user
+	if !strings.HasPrefix(profileURL, "http") {
+		profileURL = "https://soundcloud.com/" + strings.Trim(user, "/")
+	}
+
+	resolvedUsersMu.Lock()
+	id, ok := resolvedUsers[profileURL]
+	resolvedUsersMu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	body, err := soundcloudGet(fmt.Sprintf("https://api-v2.soundcloud.com/resolve?url=%s&client_id=%s",
+		url.QueryEscape(profileURL), os.Getenv("sc_client_id")))
+	if err != nil {
+		return "", err
+	}
+
+	var resolved struct {
+		ID   int    `json:"id"`
+		Kind string `json:"kind"`
+	}
+	if err := json.Unmarshal(body, &resolved); err != nil {
+		return "", err
+	}
+	if resolved.Kind != "user" || resolved.ID == 0 {
+		return "", fmt.Errorf("%s is not a SoundCloud user", profileURL)
+	}
+
+	id = strconv.Itoa(resolved.ID)
+	resolvedUsersMu.Lock()
+	resolvedUsers[profileURL] = id
+	resolvedUsersMu.Unlock()
+	log.Printf("Resolved SoundCloud user %s to %s", profileURL, id)
+	return id, nil
+}
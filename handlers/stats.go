@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const statsTopCount = 10 // Entries in the top artists and genres lists
+
+// ListeningStats is the data of the stats page
+type ListeningStats struct {
+	Plays         int
+	Mixes         int
+	HoursPlayed   float64 // Duration of the mixes played
+	HoursListened float64 // Time measured by the player's progress pings
+	TopArtists    []StatCount
+	TopGenres     []StatCount
+	Heatmap       []HeatmapRow
+	Years         []YearRecap
+}
+
+// StatCount is an entry in a top list
+type StatCount struct {
+	Name  string
+	Plays int
+	Hours float64
+}
+
+// HeatmapRow holds the plays of one weekday by hour of the day
+type HeatmapRow struct {
+	Day   string
+	Cells []HeatmapCell
+}
+
+// HeatmapCell is the number of plays in one hour of a weekday
+type HeatmapCell struct {
+	Hour    int
+	Plays   int
+	Opacity float64 // Relative to the busiest hour, for the cell colour
+}
+
+// YearRecap sums up one year of listening
+type YearRecap struct {
+	Year        int
+	Plays       int
+	Mixes       int
+	Hours       float64
+	TopArtist   string
+	TopGenre    string
+	TopMix      string
+	TopMixURL   string
+	TopMixPlays int
+}
+
+// playedTrack is the metadata a play is joined with
+type playedTrack struct {
+	Title    string
+	Artist   string
+	Genre    string
+	Duration int    // Milliseconds
+	URL      string // Empty when the track is no longer cached
+}
+
+// HandleGetStats handles the GET /stats endpoint
+func HandleGetStats(c *gin.Context) {
+	log.Printf("[GET] stats")
+
+	listeningHistoryMu.Lock()
+	history := getListeningHistory()
+	listeningHistoryMu.Unlock()
+
+	c.HTML(http.StatusOK, "stats.html", gin.H{
+		"title": "Stats | jbhicks.dev",
+		"stats": buildListeningStats(history, playedTracks(history)),
+	})
+}
+
+// playedTracks looks up the metadata of the tracks in the history, preferring the
+// cached SoundCloud tracks and falling back to what the history stored
+func playedTracks(history *ListeningHistory) map[int]playedTrack {
+	tracks := make(map[int]playedTrack)
+	for _, heard := range history.Tracks {
+		tracks[heard.TrackID] = playedTrack{
+			Title:    heard.Title,
+			Artist:   heard.Artist,
+			Genre:    heard.Genre,
+			Duration: heard.Duration,
+		}
+	}
+
+	for _, key := range soundcloudCacheKeys() {
+		mixes, err := getCachedMixes(key)
+		if err != nil {
+			continue
+		}
+		for _, item := range mixes.Collection {
+			if item.Track == nil {
+				continue
+			}
+			if _, played := tracks[item.Track.ID]; played {
+				tracks[item.Track.ID] = playedTrack{
+					Title:    item.Track.Title,
+					Artist:   item.Track.User.Username,
+					Genre:    item.Track.Genre,
+					Duration: item.Track.Duration,
+					URL:      item.Track.PermalinkURL,
+				}
+			}
+		}
+	}
+	return tracks
+}
+
+// buildListeningStats computes the stats page from the history events. Opens and
+// player starts count as plays, which recordListen stores once per listening session,
+// and progress sessions as time listened.
+func buildListeningStats(history *ListeningHistory, tracks map[int]playedTrack) ListeningStats {
+	var stats ListeningStats
+	artists := make(map[string]*StatCount)
+	genres := make(map[string]*StatCount)
+	mixes := make(map[int]bool)
+	var heatmap [7][24]int
+
+	type yearTally struct {
+		recap   YearRecap
+		artists map[string]int
+		genres  map[string]int
+		mixes   map[int]int
+	}
+	years := make(map[int]*yearTally)
+
+	for _, event := range history.Events {
+		if event.Kind == "progress" {
+			stats.HoursListened += event.Seconds / 3600
+			continue
+		}
+
+		track := tracks[event.TrackID]
+		hours := float64(track.Duration) / 3600000
+		at := event.At.Local()
+
+		stats.Plays++
+		stats.HoursPlayed += hours
+		mixes[event.TrackID] = true
+		heatmap[at.Weekday()][at.Hour()]++
+		addStatCount(artists, track.Artist, hours)
+		addStatCount(genres, track.Genre, hours)
+
+		year := years[at.Year()]
+		if year == nil {
+			year = &yearTally{
+				recap:   YearRecap{Year: at.Year()},
+				artists: make(map[string]int),
+				genres:  make(map[string]int),
+				mixes:   make(map[int]int),
+			}
+			years[at.Year()] = year
+		}
+		year.recap.Plays++
+		year.recap.Hours += hours
+		year.mixes[event.TrackID]++
+		if track.Artist != "" {
+			year.artists[track.Artist]++
+		}
+		if track.Genre != "" {
+			year.genres[track.Genre]++
+		}
+	}
+
+	stats.Mixes = len(mixes)
+	stats.TopArtists = topStatCounts(artists)
+	stats.TopGenres = topStatCounts(genres)
+	stats.Heatmap = heatmapRows(heatmap)
+
+	for _, year := range years {
+		recap := year.recap
+		recap.Mixes = len(year.mixes)
+		recap.TopArtist = mostPlayed(year.artists)
+		recap.TopGenre = mostPlayed(year.genres)
+		topMix, topMixPlays := 0, 0
+		for id, plays := range year.mixes {
+			if plays > topMixPlays || (plays == topMixPlays && id < topMix) {
+				topMix, topMixPlays = id, plays
+			}
+		}
+		recap.TopMix = tracks[topMix].Title
+		recap.TopMixURL = tracks[topMix].URL
+		recap.TopMixPlays = topMixPlays
+		stats.Years = append(stats.Years, recap)
+	}
+	sort.Slice(stats.Years, func(i, j int) bool {
+		return stats.Years[i].Year > stats.Years[j].Year
+	})
+
+	return stats
+}
+
+// addStatCount counts a play under name, skipping plays without one
+func addStatCount(counts map[string]*StatCount, name string, hours float64) {
+	if name == "" {
+		return
+	}
+	count := counts[name]
+	if count == nil {
+		count = &StatCount{Name: name}
+		counts[name] = count
+	}
+	count.Plays++
+	count.Hours += hours
+}
+
+// topStatCounts returns the most played entries of counts
+func topStatCounts(counts map[string]*StatCount) []StatCount {
+	var top []StatCount
+	for _, count := range counts {
+		top = append(top, *count)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Plays != top[j].Plays {
+			return top[i].Plays > top[j].Plays
+		}
+		return top[i].Name < top[j].Name
+	})
+	if len(top) > statsTopCount {
+		top = top[:statsTopCount]
+	}
+	return top
+}
+
+// mostPlayed returns the name with the most plays, breaking ties alphabetically
+func mostPlayed(counts map[string]int) string {
+	best, bestPlays := "", 0
+	for name, plays := range counts {
+		if plays > bestPlays || (plays == bestPlays && name < best) {
+			best, bestPlays = name, plays
+		}
+	}
+	return best
+}
+
+// heatmapRows lays out the plays by weekday and hour, starting the week on Monday
+func heatmapRows(heatmap [7][24]int) []HeatmapRow {
+	busiest := 0
+	for _, day := range heatmap {
+		for _, plays := range day {
+			if plays > busiest {
+				busiest = plays
+			}
+		}
+	}
+
+	var rows []HeatmapRow
+	for i := 1; i <= 7; i++ {
+		day := time.Weekday(i % 7)
+		row := HeatmapRow{Day: day.String()[:3]}
+		for hour, plays := range heatmap[day] {
+			cell := HeatmapCell{Hour: hour, Plays: plays}
+			if busiest > 0 && plays > 0 {
+				// Keep quiet hours visible next to the empty ones
+				cell.Opacity = 0.15 + 0.85*float64(plays)/float64(busiest)
+			}
+			row.Cells = append(row.Cells, cell)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
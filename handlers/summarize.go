@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	summaryMaxSentences = 3
+	minSummaryWords     = 60 // Texts shorter than this are already their own summary
+	textRankDamping     = 0.85
+	textRankIterations  = 30
+)
+
+// Common English words that carry no meaning when comparing sentences
+var stopWords = map[string]bool{
+	"a": true, "about": true, "after": true, "all": true, "also": true, "an": true,
+	"and": true, "any": true, "are": true, "as": true, "at": true, "be": true,
+	"been": true, "but": true, "by": true, "can": true, "could": true, "did": true,
+	"do": true, "does": true, "for": true, "from": true, "had": true, "has": true,
+	"have": true, "he": true, "her": true, "his": true, "how": true, "i": true,
+	"if": true, "in": true, "into": true, "is": true, "it": true, "its": true,
+	"just": true, "more": true, "most": true, "new": true, "no": true, "not": true,
+	"of": true, "on": true, "one": true, "or": true, "our": true, "out": true,
+	"over": true, "said": true, "she": true, "so": true, "some": true, "than": true,
+	"that": true, "the": true, "their": true, "them": true, "then": true, "there": true,
+	"these": true, "they": true, "this": true, "to": true, "up": true, "was": true,
+	"we": true, "were": true, "what": true, "when": true, "which": true, "who": true,
+	"will": true, "with": true, "would": true, "you": true, "your": true,
+}
+
+// SummarizeNews computes extractive summaries for cached news items
+// from their extracted article bodies, falling back to the feed content
+func SummarizeNews() {
+	updateNewsCache(func(news *NewsResponse) {
+		summarized := 0
+		for i := range news.Collection {
+			item := &news.Collection[i]
+			item.Summary = summarize(itemText(item), summaryMaxSentences)
+			if len(item.Summary) > 0 {
+				summarized++
+			}
+		}
+		log.Printf("Summarized %d of %d news items", summarized, len(news.Collection))
+	})
+}
+
+// itemText returns the best available body text for an item
+func itemText(item *Item) string {
+	if article := getCachedArticle(item.Link); article != nil && article.Text != "" {
+		return article.Text
+	}
+	if item.Content != "" {
+		return htmlToText(item.Content)
+	}
+	return htmlToText(item.Description)
+}
+
+// htmlToText strips the markup from an HTML fragment
+func htmlToText(fragment string) string {
+	doc, err := html.Parse(strings.NewReader(fragment))
+	if err != nil {
+		return ""
+	}
+	return collapseWhitespace(blockText(doc))
+}
+
+// summarize picks the most central sentences of text using TextRank,
+// returned in the order they appear in the text
+func summarize(text string, maxSentences int) []string {
+	if len(strings.Fields(text)) < minSummaryWords {
+		return nil
+	}
+
+	sentences := splitSentences(text)
+	if len(sentences) <= maxSentences {
+		return nil
+	}
+	if len(sentences) < 8 {
+		maxSentences = 2
+	}
+
+	words := make([]map[string]bool, len(sentences))
+	for i, sentence := range sentences {
+		words[i] = sentenceWords(sentence)
+	}
+
+	// Build the similarity graph between every pair of sentences
+	n := len(sentences)
+	weights := make([][]float64, n)
+	totals := make([]float64, n)
+	for i := range weights {
+		weights[i] = make([]float64, n)
+	}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			similarity := sentenceSimilarity(words[i], words[j])
+			weights[i][j] = similarity
+			weights[j][i] = similarity
+			totals[i] += similarity
+			totals[j] += similarity
+		}
+	}
+
+	// Iterate the weighted PageRank of each sentence
+	scores := make([]float64, n)
+	for i := range scores {
+		scores[i] = 1
+	}
+	for iteration := 0; iteration < textRankIterations; iteration++ {
+		next := make([]float64, n)
+		for i := 0; i < n; i++ {
+			sum := 0.0
+			for j := 0; j < n; j++ {
+				if weights[j][i] > 0 && totals[j] > 0 {
+					sum += weights[j][i] / totals[j] * scores[j]
+				}
+			}
+			next[i] = (1 - textRankDamping) + textRankDamping*sum
+		}
+		scores = next
+	}
+
+	ranked := make([]int, n)
+	for i := range ranked {
+		ranked[i] = i
+	}
+	sort.SliceStable(ranked, func(a, b int) bool {
+		return scores[ranked[a]] > scores[ranked[b]]
+	})
+
+	chosen := ranked[:maxSentences]
+	sort.Ints(chosen)
+
+	var summary []string
+	for _, i := range chosen {
+		summary = append(summary, sentences[i])
+	}
+	return summary
+}
+
+// splitSentences breaks text into sentences on terminal punctuation and line breaks
+func splitSentences(text string) []string {
+	var sentences []string
+	for _, line := range strings.Split(text, "\n") {
+		start := 0
+		runes := []rune(line)
+		for i, r := range runes {
+			if r != '.' && r != '!' && r != '?' {
+				continue
+			}
+			// A sentence ends at punctuation followed by a space and a capital letter or quote
+			if i+2 < len(runes) && runes[i+1] == ' ' && (unicode.IsUpper(runes[i+2]) || runes[i+2] == '"' || runes[i+2] == '“') {
+				sentences = appendSentence(sentences, string(runes[start:i+1]))
+				start = i + 2
+			}
+		}
+		sentences = appendSentence(sentences, string(runes[start:]))
+	}
+	return sentences
+}
+
+// appendSentence adds sentence if it is long enough to be worth summarizing with
+func appendSentence(sentences []string, sentence string) []string {
+	sentence = strings.TrimSpace(sentence)
+	if len(strings.Fields(sentence)) < 5 {
+		return sentences
+	}
+	return append(sentences, sentence)
+}
+
+// sentenceWords returns the set of meaningful lowercase words in a sentence
+func sentenceWords(sentence string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(sentence), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		if len(word) > 1 && !stopWords[word] {
+			words[word] = true
+		}
+	}
+	return words
+}
+
+// sentenceSimilarity is the TextRank overlap measure between two sentences
+func sentenceSimilarity(a map[string]bool, b map[string]bool) float64 {
+	if len(a) < 2 || len(b) < 2 {
+		return 0
+	}
+
+	overlap := 0
+	for word := range a {
+		if b[word] {
+			overlap++
+		}
+	}
+	if overlap == 0 {
+		return 0
+	}
+
+	return float64(overlap) / (math.Log(float64(len(a))) + math.Log(float64(len(b))))
+}
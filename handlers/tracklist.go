@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const minTracklistLines = 3 // Lines needed before a description counts as a tracklist
+
+var (
+	// "00:00 Artist - Title", "[12:34] Artist - Title", "1. (1:02:03) Artist - Title"
+	timedTracklistLine = regexp.MustCompile(`^(?:\d{1,3}[.)]\s*)?[\[(]?((?:\d{1,2}:)?\d{1,3}:\d{2})[\])]?\s*(?:[-–—|.:]\s*)?(.+)$`)
+	// "1. Artist - Title", "01) Artist - Title", "12 - Artist - Title"
+	numberedTracklistLine = regexp.MustCompile(`^\d{1,3}(?:[.)]|\s+[-–—])\s+(.+)$`)
+	chapterSeparator      = regexp.MustCompile(`\s+[-–—]\s+`)
+)
+
+// Chapter is a track in the tracklist of a mix
+type Chapter struct {
+	Number    int    `json:"number"`
+	Start     int    `json:"start"`      // Seconds into the mix, or -1 for tracklists without times
+	StartText string `json:"start_text"` // Start formatted for display
+	Artist    string `json:"artist"`
+	Title     string `json:"title"`
+	URL       string `json:"url"` // Permalink at the start of the chapter, empty without a time
+}
+
+// parseTracklist finds the tracklist in the description of a mix. Timestamped lines
+// are preferred, falling back to a numbered list without times.
+func parseTracklist(description string, permalinkURL string) []Chapter {
+	var timed, numbered []Chapter
+	for _, line := range strings.Split(description, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if match := timedTracklistLine.FindStringSubmatch(line); match != nil {
+			if start, ok := parseChapterTime(match[1]); ok {
+				if chapter, ok := newChapter(match[2]); ok {
+					chapter.Start = start
+					chapter.StartText = formatChapterTime(start)
+					chapter.URL = fmt.Sprintf("%s#t=%d:%02d", permalinkURL, start/60, start%60)
+					timed = append(timed, chapter)
+				}
+				continue
+			}
+		}
+
+		if match := numberedTracklistLine.FindStringSubmatch(line); match != nil {
+			if chapter, ok := newChapter(match[1]); ok {
+				chapter.Start = -1
+				numbered = append(numbered, chapter)
+			}
+		}
+	}
+
+	chapters := timed
+	if len(chapters) < minTracklistLines {
+		chapters = numbered
+	}
+	if len(chapters) < minTracklistLines {
+		return nil
+	}
+	for i := range chapters {
+		chapters[i].Number = i + 1
+	}
+	return chapters
+}
+
+// newChapter splits the text of a tracklist line into artist and title
+func newChapter(text string) (Chapter, bool) {
+	text = strings.TrimSpace(strings.TrimLeft(text, "-–—|•*. "))
+	if !strings.ContainsAny(strings.ToLower(text), "abcdefghijklmnopqrstuvwxyz") {
+		return Chapter{}, false
+	}
+
+	parts := chapterSeparator.Split(text, 2)
+	if len(parts) == 2 {
+		return Chapter{Artist: parts[0], Title: parts[1]}, true
+	}
+	return Chapter{Title: text}, true
+}
+
+// parseChapterTime parses "mm:ss" or "h:mm:ss" into seconds
+func parseChapterTime(s string) (int, bool) {
+	seconds := 0
+	for _, part := range strings.Split(s, ":") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, false
+		}
+		seconds = seconds*60 + n
+	}
+	return seconds, true
+}
+
+// formatChapterTime formats seconds as "m:ss", or "h:mm:ss" from an hour on
+func formatChapterTime(seconds int) string {
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTracklist(t *testing.T) {
+	const permalink = "https://soundcloud.com/dj/mix"
+
+	tests := []struct {
+		name        string
+		description string
+		want        []Chapter
+	}{
+		{
+			name:        "timed lines",
+			description: "Recorded live\n\n00:00 Artist A - Track One\n05:30 Artist B - Track Two\n1:02:03 Artist C – Track Three",
+			want: []Chapter{
+				{Number: 1, Start: 0, StartText: "0:00", Artist: "Artist A", Title: "Track One", URL: permalink + "#t=0:00"},
+				{Number: 2, Start: 330, StartText: "5:30", Artist: "Artist B", Title: "Track Two", URL: permalink + "#t=5:30"},
+				{Number: 3, Start: 3723, StartText: "1:02:03", Artist: "Artist C", Title: "Track Three", URL: permalink + "#t=62:03"},
+			},
+		},
+		{
+			name:        "bracketed times and a line without artist",
+			description: "[00:00] Artist A - One\n[12:34] Artist B - Two\n[25:00] ID",
+			want: []Chapter{
+				{Number: 1, Start: 0, StartText: "0:00", Artist: "Artist A", Title: "One", URL: permalink + "#t=0:00"},
+				{Number: 2, Start: 754, StartText: "12:34", Artist: "Artist B", Title: "Two", URL: permalink + "#t=12:34"},
+				{Number: 3, Start: 1500, StartText: "25:00", Title: "ID", URL: permalink + "#t=25:00"},
+			},
+		},
+		{
+			name:        "numbered lines with times",
+			description: "1. (0:00) Artist A - One\n2. (4:00) Artist B - Two\n3. (8:00) Artist C - Three",
+			want: []Chapter{
+				{Number: 1, Start: 0, StartText: "0:00", Artist: "Artist A", Title: "One", URL: permalink + "#t=0:00"},
+				{Number: 2, Start: 240, StartText: "4:00", Artist: "Artist B", Title: "Two", URL: permalink + "#t=4:00"},
+				{Number: 3, Start: 480, StartText: "8:00", Artist: "Artist C", Title: "Three", URL: permalink + "#t=8:00"},
+			},
+		},
+		{
+			name:        "numbered lines without times",
+			description: "Tracklist:\n1. Artist A - One\n2. Artist B - Two\n3) Artist C - Three",
+			want: []Chapter{
+				{Number: 1, Start: -1, Artist: "Artist A", Title: "One"},
+				{Number: 2, Start: -1, Artist: "Artist B", Title: "Two"},
+				{Number: 3, Start: -1, Artist: "Artist C", Title: "Three"},
+			},
+		},
+		{
+			name:        "too few lines",
+			description: "00:00 Artist A - One\n05:00 Artist B - Two",
+			want:        nil,
+		},
+		{
+			name:        "times without text",
+			description: "00:00 -\n05:00 |\n10:00\n15:00 Artist A - One",
+			want:        nil,
+		},
+		{
+			name:        "no tracklist",
+			description: "Thanks for listening! Bookings: mail@example.com",
+			want:        nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseTracklist(tt.description, permalink)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTracklist() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseChapterTime(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   int
+		wantOK bool
+	}{
+		{"0:00", 0, true},
+		{"5:30", 330, true},
+		{"90:00", 5400, true},
+		{"1:02:03", 3723, true},
+		{"1:x", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseChapterTime(tt.in)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseChapterTime(%q) = %d, %v, want %d, %v", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestFormatChapterTime(t *testing.T) {
+	tests := []struct {
+		in   int
+		want string
+	}{
+		{0, "0:00"},
+		{65, "1:05"},
+		{3599, "59:59"},
+		{3600, "1:00:00"},
+		{3723, "1:02:03"},
+	}
+
+	for _, tt := range tests {
+		if got := formatChapterTime(tt.in); got != tt.want {
+			t.Errorf("formatChapterTime(%d) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
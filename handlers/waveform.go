@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	waveformCacheDir      = "waveform-cache"
+	waveformBars          = 120 // Bars drawn per waveform
+	waveformHeight        = 40  // Height of the SVG view box
+	waveformPrefetchLimit = 4   // Concurrent fetches while prefetching waveforms
+	waveformPrefetchMax   = 200 // Waveforms fetched per prefetch, so a long favorites history spreads over several runs
+)
+
+// WaveformBar is one bar of the SVG waveform on a mix card, in view box units
+type WaveformBar struct {
+	X      int
+	Y      float64
+	Height float64
+	Heard  bool // Before the furthest position listened to
+}
+
+// cachedWaveform is a downsampled waveform, with peaks from 0 to 100
+type cachedWaveform struct {
+	TrackID int   `json:"trackId"`
+	Peaks   []int `json:"peaks"`
+}
+
+var (
+	waveformCacheMu sync.Mutex
+	// Waveforms read from or written to the cache, nil for tracks without one, so
+	// rendering the mix cards reads each file at most once
+	waveformCache = make(map[int]*cachedWaveform)
+)
+
+// PrefetchWaveforms fetches and caches the waveforms of the cached tracks that don't have one yet
+func PrefetchWaveforms() {
+	var tracks []*Track
+	seen := make(map[int]bool)
+	for _, key := range soundcloudCacheKeys() {
+		mixes, err := getCachedMixes(key)
+		if err != nil {
+			continue
+		}
+		for _, item := range mixes.Collection {
+			track := item.Track
+			if track == nil || track.WaveformURL == "" || seen[track.ID] || getCachedWaveform(track.ID) != nil {
+				continue
+			}
+			seen[track.ID] = true
+			tracks = append(tracks, track)
+		}
+	}
+	if len(tracks) > waveformPrefetchMax {
+		tracks = tracks[:waveformPrefetchMax]
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, waveformPrefetchLimit)
+	for _, track := range tracks {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(track *Track) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := fetchWaveform(track); err != nil {
+				log.Printf("Error fetching waveform of track %d: %v", track.ID, err)
+			}
+		}(track)
+	}
+	wg.Wait()
+	log.Printf("Prefetched %d waveforms", len(tracks))
+}
+
+// fetchWaveform downloads the waveform of track, downsamples it and stores it in the cache
+func fetchWaveform(track *Track) error {
+	// Older payloads link the PNG rendering, which has a JSON sibling with the samples
+	waveformURL := strings.TrimSuffix(track.WaveformURL, ".png")
+	if !strings.HasSuffix(waveformURL, ".json") {
+		waveformURL += ".json"
+	}
+
+	// Waveforms are public on the CDN, so the SoundCloud credentials aren't sent
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(waveformURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	var waveform struct {
+		Samples []int `json:"samples"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&waveform); err != nil {
+		return err
+	}
+	if len(waveform.Samples) == 0 {
+		return errors.New("waveform has no samples")
+	}
+
+	return storeCachedWaveform(&cachedWaveform{TrackID: track.ID, Peaks: downsampleWaveform(waveform.Samples, waveformBars)})
+}
+
+// downsampleWaveform reduces samples to the peak of each of n segments, scaled to 0-100
+// against the loudest sample
+func downsampleWaveform(samples []int, n int) []int {
+	if len(samples) < n {
+		n = len(samples)
+	}
+
+	loudest := 0
+	for _, sample := range samples {
+		if sample > loudest {
+			loudest = sample
+		}
+	}
+
+	peaks := make([]int, n)
+	for i := range peaks {
+		start := i * len(samples) / n
+		end := (i + 1) * len(samples) / n
+		for _, sample := range samples[start:end] {
+			if sample > peaks[i] {
+				peaks[i] = sample
+			}
+		}
+		if loudest > 0 {
+			peaks[i] = peaks[i] * 100 / loudest
+		}
+	}
+	return peaks
+}
+
+// attachWaveforms sets the SVG bars of the tracks in items that have a cached
+// waveform. Tracks must have been marked as heard first to shade the heard part.
+func attachWaveforms(items []TrackItem) {
+	for _, item := range items {
+		if item.Track == nil {
+			continue
+		}
+		waveform := getCachedWaveform(item.Track.ID)
+		if waveform == nil {
+			continue
+		}
+
+		heardBars := len(waveform.Peaks) * item.Track.HeardPercent / 100
+		bars := make([]WaveformBar, len(waveform.Peaks))
+		for i, peak := range waveform.Peaks {
+			// Keep silent parts visible as a thin line
+			height := math.Round(float64(peak)*waveformHeight/10) / 10
+			if height < 1 {
+				height = 1
+			}
+			bars[i] = WaveformBar{
+				X:      i,
+				Y:      math.Round((waveformHeight-height)*5) / 10,
+				Height: height,
+				Heard:  i < heardBars,
+			}
+		}
+		item.Track.Waveform = bars
+	}
+}
+
+// WaveformViewBox returns the view box of the track's SVG waveform
+func (t Track) WaveformViewBox() string {
+	return fmt.Sprintf("0 0 %d %d", len(t.Waveform), waveformHeight)
+}
+
+// waveformCachePath returns the cache file used for the waveform of a track
+func waveformCachePath(trackID int) string {
+	return filepath.Join(waveformCacheDir, fmt.Sprintf("%d.json", trackID))
+}
+
+// getCachedWaveform returns the cached waveform of a track, or nil if it isn't cached
+func getCachedWaveform(trackID int) *cachedWaveform {
+	waveformCacheMu.Lock()
+	defer waveformCacheMu.Unlock()
+
+	if waveform, ok := waveformCache[trackID]; ok {
+		return waveform
+	}
+	var waveform *cachedWaveform
+	if err := readJSONFile(waveformCachePath(trackID), &waveform); err != nil {
+		waveform = nil
+	}
+	waveformCache[trackID] = waveform
+	return waveform
+}
+
+// storeCachedWaveform writes a downsampled waveform to the cache
+func storeCachedWaveform(waveform *cachedWaveform) error {
+	waveformCacheMu.Lock()
+	defer waveformCacheMu.Unlock()
+
+	if err := os.MkdirAll(waveformCacheDir, 0755); err != nil {
+		return err
+	}
+	if err := writeJSONFile(waveformCachePath(waveform.TrackID), waveform); err != nil {
+		return err
+	}
+	waveformCache[waveform.TrackID] = waveform
+	return nil
+}
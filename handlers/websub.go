@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	webSubSubscriptionsFile = "websub-subscriptions.json"
+	webSubLeaseSeconds      = 7 * 24 * 60 * 60
+	webSubRenewBefore       = 24 * time.Hour // Renew leases expiring sooner than this
+	maxWebSubBodyBytes      = 5 << 20
+)
+
+// WebSubSubscription tracks a push subscription to a feed's hub
+type WebSubSubscription struct {
+	ID          string    `json:"id"`
+	Hub         string    `json:"hub"`
+	Topic       string    `json:"topic"`
+	Source      string    `json:"source"`
+	Secret      string    `json:"secret"`
+	State       string    `json:"state"` // "pending", "active" or "denied"
+	RequestedAt time.Time `json:"requestedAt"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+var webSubMu sync.Mutex
+
+// webSubCallbackBase returns the public URL the hub can reach us on, from the
+// public_base_url env var. WebSub is disabled when it isn't set.
+func webSubCallbackBase() string {
+	return strings.TrimRight(os.Getenv("public_base_url"), "/")
+}
+
+// HandleGetWebSubCallback handles the GET /websub/callback/:id endpoint, where hubs verify intent
+func HandleGetWebSubCallback(c *gin.Context) {
+	id := c.Param("id")
+	mode := c.Query("hub.mode")
+	topic := c.Query("hub.topic")
+	log.Printf("[GET] websub callback %s %s", id, mode)
+
+	webSubMu.Lock()
+	defer webSubMu.Unlock()
+
+	subscriptions := getWebSubSubscriptions()
+	subscription, ok := subscriptions[id]
+	if !ok || subscription.Topic != topic {
+		c.String(http.StatusNotFound, "Unknown subscription")
+		return
+	}
+
+	switch mode {
+	case "subscribe":
+		lease, err := strconv.Atoi(c.Query("hub.lease_seconds"))
+		if err != nil || lease <= 0 {
+			lease = webSubLeaseSeconds
+		}
+		subscription.State = "active"
+		subscription.ExpiresAt = time.Now().Add(time.Duration(lease) * time.Second)
+	case "denied":
+		log.Printf("WebSub subscription to %s denied: %s", topic, c.Query("hub.reason"))
+		subscription.State = "denied"
+	default:
+		// We never unsubscribe, so any other verification is not ours
+		c.String(http.StatusNotFound, "Unexpected mode")
+		return
+	}
+
+	subscriptions[id] = subscription
+	if err := writeJSONFile(webSubSubscriptionsFile, subscriptions); err != nil {
+		log.Printf("Error storing WebSub subscriptions: %v", err)
+	}
+
+	c.String(http.StatusOK, c.Query("hub.challenge"))
+}
+
+// HandlePostWebSubCallback handles the POST /websub/callback/:id endpoint, where hubs push new content
+func HandlePostWebSubCallback(c *gin.Context) {
+	id := c.Param("id")
+	log.Printf("[POST] websub callback %s", id)
+
+	webSubMu.Lock()
+	subscription, ok := getWebSubSubscriptions()[id]
+	webSubMu.Unlock()
+	if !ok {
+		c.String(http.StatusNotFound, "Unknown subscription")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebSubBodyBytes))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Failed to read body")
+		return
+	}
+
+	// Hubs expect a 2xx even for content we discard, so invalid pushes are only logged
+	if !validWebSubSignature(subscription.Secret, c.GetHeader("X-Hub-Signature"), body) {
+		log.Printf("Ignoring WebSub push for %s with an invalid signature", subscription.Topic)
+		c.Status(http.StatusAccepted)
+		return
+	}
+
+	rss, err := parseFeed(body)
+	if err != nil {
+		log.Printf("Error parsing WebSub push for %s: %v", subscription.Topic, err)
+		c.Status(http.StatusAccepted)
+		return
+	}
+
+	items := rssItems(rss, subscription.Source)
+	enrichHackerNews(items)
+	added := mergeNewsItems(items)
+	log.Printf("Merged %d pushed items (%d new) from %s", len(items), added, subscription.Source)
+
+	c.Status(http.StatusAccepted)
+}
+
+// validWebSubSignature checks an X-Hub-Signature header ("method=hexdigest") against the body
+func validWebSubSignature(secret string, header string, body []byte) bool {
+	if secret == "" {
+		return true
+	}
+
+	parts := strings.SplitN(header, "=", 2)
+	if len(parts) != 2 {
+		return false
+	}
+
+	var newHash func() hash.Hash
+	switch parts[0] {
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha384":
+		newHash = sha512.New384
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return false
+	}
+
+	signature, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(signature, mac.Sum(nil))
+}
+
+// ensureWebSubSubscription subscribes to topic at hub unless an active or
+// pending subscription already exists. It does nothing when WebSub is disabled,
+// in which case the feed is only polled.
+func ensureWebSubSubscription(hub string, topic string, source string) {
+	base := webSubCallbackBase()
+	if base == "" {
+		return
+	}
+
+	webSubMu.Lock()
+	defer webSubMu.Unlock()
+
+	subscriptions := getWebSubSubscriptions()
+	id := itemID(topic, "")
+	subscription, ok := subscriptions[id]
+	if ok && subscription.Hub == hub {
+		switch subscription.State {
+		case "active":
+			if time.Until(subscription.ExpiresAt) > webSubRenewBefore {
+				return
+			}
+		case "pending":
+			// Give the hub an hour to verify before asking again
+			if time.Since(subscription.RequestedAt) < time.Hour {
+				return
+			}
+		case "denied":
+			return
+		}
+	}
+
+	// Renewals keep the secret so pushes signed before the hub verifies still validate
+	secret := subscription.Secret
+	if !ok || secret == "" {
+		var err error
+		if secret, err = randomSecret(); err != nil {
+			log.Printf("Error generating WebSub secret: %v", err)
+			return
+		}
+	}
+
+	// An active subscription stays active until the hub verifies the renewal
+	state := "pending"
+	if ok && subscription.State == "active" {
+		state = "active"
+	}
+
+	subscription = WebSubSubscription{
+		ID:          id,
+		Hub:         hub,
+		Topic:       topic,
+		Source:      source,
+		Secret:      secret,
+		State:       state,
+		RequestedAt: time.Now(),
+		ExpiresAt:   subscription.ExpiresAt,
+	}
+	subscriptions[id] = subscription
+	if err := writeJSONFile(webSubSubscriptionsFile, subscriptions); err != nil {
+		log.Printf("Error storing WebSub subscriptions: %v", err)
+		return
+	}
+
+	callback := fmt.Sprintf("%s/websub/callback/%s", base, id)
+	go requestWebSubSubscription(hub, topic, callback, secret)
+}
+
+// requestWebSubSubscription sends the subscription request to the hub, which verifies it asynchronously
+func requestWebSubSubscription(hub string, topic string, callback string, secret string) {
+	log.Printf("Subscribing to %s via WebSub hub %s", topic, hub)
+
+	form := url.Values{
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {topic},
+		"hub.callback":      {callback},
+		"hub.secret":        {secret},
+		"hub.lease_seconds": {strconv.Itoa(webSubLeaseSeconds)},
+	}
+
+	client := &http.Client{Timeout: 20 * time.Second}
+	resp, err := client.PostForm(hub, form)
+	if err != nil {
+		log.Printf("Error subscribing to %s: %v", topic, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		log.Printf("WebSub hub %s rejected subscription to %s: %d", hub, topic, resp.StatusCode)
+	}
+}
+
+// getWebSubSubscriptions reads the stored subscriptions keyed by id. Callers must hold webSubMu.
+func getWebSubSubscriptions() map[string]WebSubSubscription {
+	subscriptions := make(map[string]WebSubSubscription)
+	if err := readJSONFile(webSubSubscriptionsFile, &subscriptions); err != nil {
+		return make(map[string]WebSubSubscription)
+	}
+	return subscriptions
+}
+
+// randomSecret returns a random hex string suitable for HMAC signing
+func randomSecret() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
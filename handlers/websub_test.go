@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// hubSignature signs body the way a hub does for the X-Hub-Signature header
+func hubSignature(method string, newHash func() hash.Hash, secret string, body string) string {
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write([]byte(body))
+	return method + "=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestValidWebSubSignature(t *testing.T) {
+	const secret = "s3cret"
+	const body = `<?xml version="1.0"?><rss><channel><title>Feed</title></channel></rss>`
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   string
+		want   bool
+	}{
+		{"no secret accepts anything", "", "", body, true},
+		{"sha1", secret, hubSignature("sha1", sha1.New, secret, body), body, true},
+		{"sha256", secret, hubSignature("sha256", sha256.New, secret, body), body, true},
+		{"sha384", secret, hubSignature("sha384", sha512.New384, secret, body), body, true},
+		{"sha512", secret, hubSignature("sha512", sha512.New, secret, body), body, true},
+		{"uppercase hex digest", secret, "sha1=" + strings.ToUpper(strings.TrimPrefix(hubSignature("sha1", sha1.New, secret, body), "sha1=")), body, true},
+		{"missing header", secret, "", body, false},
+		{"no method", secret, strings.TrimPrefix(hubSignature("sha1", sha1.New, secret, body), "sha1="), body, false},
+		{"unsupported method", secret, "md5=0123456789abcdef0123456789abcdef", body, false},
+		{"method mismatch", secret, "sha256=" + strings.TrimPrefix(hubSignature("sha1", sha1.New, secret, body), "sha1="), body, false},
+		{"digest not hex", secret, "sha1=not-hex", body, false},
+		{"other secret", secret, hubSignature("sha1", sha1.New, "guess", body), body, false},
+		{"tampered body", secret, hubSignature("sha1", sha1.New, secret, body), body + " ", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validWebSubSignature(tt.secret, tt.header, []byte(tt.body)); got != tt.want {
+				t.Errorf("validWebSubSignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFeedAtomContent(t *testing.T) {
+	const feed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Blog</title>
+  <entry>
+    <id>urn:1</id>
+    <title>Text</title>
+    <summary>Fish &amp; chips</summary>
+  </entry>
+  <entry>
+    <id>urn:2</id>
+    <title>Escaped HTML</title>
+    <content type="html">&lt;p&gt;Hello &lt;b&gt;world&lt;/b&gt;&lt;/p&gt;</content>
+  </entry>
+  <entry>
+    <id>urn:3</id>
+    <title>XHTML</title>
+    <content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Hello <b>world</b></p></div></content>
+  </entry>
+</feed>`
+
+	rss, err := parseFeed([]byte(feed))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct{ description, content string }{
+		{"Fish & chips", ""},
+		{"<p>Hello <b>world</b></p>", "<p>Hello <b>world</b></p>"},
+		{`<div xmlns="http://www.w3.org/1999/xhtml"><p>Hello <b>world</b></p></div>`, `<div xmlns="http://www.w3.org/1999/xhtml"><p>Hello <b>world</b></p></div>`},
+	}
+	if len(rss.Channel.Items) != len(want) {
+		t.Fatalf("got %d items, want %d", len(rss.Channel.Items), len(want))
+	}
+	for i, item := range rss.Channel.Items {
+		if item.Description != want[i].description || item.Content != want[i].content {
+			t.Errorf("item %d: description %q, content %q, want %q, %q", i, item.Description, item.Content, want[i].description, want[i].content)
+		}
+	}
+}
+
+func TestKeepPushedItems(t *testing.T) {
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	item := func(id string, source string, hours int) Item {
+		return Item{ID: id, Source: source, PublishedAt: start.Add(time.Duration(hours) * time.Hour)}
+	}
+
+	fetched := []Item{item("a1", "A", 0), item("a2", "A", 2), item("b1", "B", 1)}
+	previous := []Item{
+		item("a1", "A", 0),  // still listed
+		item("a3", "A", 3),  // pushed, newer than the feed
+		item("a0", "A", -1), // fell off the feed
+		item("b2", "B", 2),  // pushed
+		item("c1", "C", 5),  // source not fetched this time
+	}
+
+	var ids []string
+	for _, item := range keepPushedItems(fetched, previous) {
+		ids = append(ids, item.ID)
+	}
+	want := []string{"a1", "a2", "b1", "a3", "b2"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("keepPushedItems() = %v, want %v", ids, want)
+	}
+}
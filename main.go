@@ -1,8 +1,10 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -11,6 +13,7 @@ import (
 
 func setupRouter() *gin.Engine {
 	r := gin.New()
+	r.SetFuncMap(handlers.TemplateFuncs())
 	r.LoadHTMLGlob("templates/*")
 
 	// Health chek
@@ -20,13 +23,44 @@ func setupRouter() *gin.Engine {
 
 	r.GET("/", func(c *gin.Context) {
 		c.HTML(http.StatusOK, "index.html", gin.H{
-			"title": "jbhicks.dev",
+			"title":     "jbhicks.dev",
+			"favorites": handlers.FavoritesTabs(),
 		})
 	})
 
 	r.GET("/api/soundcloud/stream", handlers.HandleGetSoundcloudStream)
 	r.GET("/api/soundcloud/favorites", handlers.HandleGetSoundcloudFavorites)
+	r.GET("/api/soundcloud/favorites/:name", handlers.HandleGetSoundcloudUserFavorites)
+	r.GET("/api/soundcloud/playlists/:id", handlers.HandleGetSoundcloudPlaylist)
+	r.GET("/api/soundcloud/export/:key/:format", handlers.HandleGetSoundcloudExport)
+	r.GET("/api/soundcloud/tracks/:id/stream", handlers.HandleGetMixStream)
+	r.POST("/api/soundcloud/tracks/:id/position", handlers.HandlePostMixPosition)
+	r.GET("/go/mix/:id", handlers.HandleGetMixRedirect)
+	r.GET("/img", handlers.HandleGetImage)
+	r.GET("/api/queue", handlers.HandleGetQueue)
+	r.POST("/api/queue", handlers.HandlePostQueue)
+	r.POST("/api/queue/clear", handlers.HandlePostQueueClear)
+	r.POST("/api/queue/autofill", handlers.HandlePostQueueAutofill)
+	r.POST("/api/queue/:id/play", handlers.HandlePostQueuePlay)
+	r.POST("/api/queue/:id/move", handlers.HandlePostQueueMove)
+	r.DELETE("/api/queue/:id", handlers.HandleDeleteQueueEntry)
 	r.GET("/api/news", handlers.HandleGetNews)
+	r.GET("/api/events", handlers.HandleGetEvents)
+	r.POST("/api/saved/toggle", handlers.HandlePostSavedToggle)
+	r.POST("/api/saved/:id", handlers.HandlePostSavedItem)
+	r.DELETE("/api/saved/:id", handlers.HandleDeleteSavedItem)
+	r.GET("/saved", handlers.HandleGetSaved)
+	r.GET("/stats", handlers.HandleGetStats)
+	r.POST("/api/snapshots", handlers.HandlePostSnapshot)
+	r.GET("/snapshots/:id", handlers.HandleGetSnapshot)
+	r.GET("/digest/today.epub", handlers.HandleGetDigestEPUB)
+	r.GET("/saved/export.json", handlers.HandleGetSavedExportJSON)
+	r.GET("/saved/export.html", handlers.HandleGetSavedExportBookmarks)
+	r.GET("/read", handlers.HandleGetReader)
+	r.GET("/websub/callback/:id", handlers.HandleGetWebSubCallback)
+	r.POST("/websub/callback/:id", handlers.HandlePostWebSubCallback)
+	r.GET("/api/podcasts/player", handlers.HandleGetPodcastPlayer)
+	r.POST("/api/podcasts/position", handlers.HandlePostPodcastPosition)
 
 	r.Static("/static", "./static")
 	r.Static("/templates", "./templates")
@@ -34,20 +68,56 @@ func setupRouter() *gin.Engine {
 	return r
 }
 
+// runDigestCommand writes the daily news digest EPUB to disk
+func runDigestCommand(args []string) {
+	flags := flag.NewFlagSet("digest", flag.ExitOnError)
+	output := flags.String("o", handlers.DigestFilename(time.Now()), "path to write the EPUB to")
+	flags.Parse(args)
+
+	epub, err := handlers.BuildDigestEPUB(time.Now())
+	if err != nil {
+		log.Fatalf("Error building digest: %v", err)
+	}
+	if err := os.WriteFile(*output, epub, 0644); err != nil {
+		log.Fatalf("Error writing digest: %v", err)
+	}
+	log.Printf("Wrote digest to %s", *output)
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "digest" {
+		runDigestCommand(os.Args[2:])
+		return
+	}
+
+	if err := handlers.InitImageProxy(); err != nil {
+		log.Fatalf("Error configuring image proxy: %v", err)
+	}
 
 	go func() {
 		// Initial load of data
 		log.Println("Initial loading of cache...")
 		handlers.LoadCache("soundcloud-stream", true)
-		handlers.LoadCache("soundcloud-favorites", false)
+		for _, key := range handlers.FavoritesCacheKeys() {
+			handlers.LoadCache(key, false)
+		}
+		handlers.PrefetchWaveforms()
 		handlers.LoadNewsCache()
+		handlers.PrefetchArticles()
+		handlers.SummarizeNews()
+		handlers.MaybeSendEmailDigest()
 
 		for range time.Tick(1 * time.Hour) { // Run this loop once every hour
 			log.Println("Loading cache...")
 			handlers.LoadCache("soundcloud-stream", true)
-			handlers.LoadCache("soundcloud-favorites", false)
+			for _, key := range handlers.FavoritesCacheKeys() {
+				handlers.LoadCache(key, false)
+			}
+			handlers.PrefetchWaveforms()
 			handlers.LoadNewsCache()
+			handlers.PrefetchArticles()
+			handlers.SummarizeNews()
+			handlers.MaybeSendEmailDigest()
 		}
 	}()
 